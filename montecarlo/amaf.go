@@ -0,0 +1,101 @@
+package montecarlo
+
+// PlayedAction is an action taken during an iteration of a search, along with
+// the player who took it.
+type PlayedAction struct {
+	Player uint
+	Key    Key
+}
+
+// amafStats are the all-moves-as-first statistics of a single action.
+type amafStats struct {
+	score  []float64
+	visits int64
+}
+
+// amafTable holds the all-moves-as-first statistics of each action taken by a
+// node's player, at any point after the node (see RAVEPolicy).
+type amafTable struct {
+	actions map[Key]*amafStats
+}
+
+// AMAFVisits returns the number of iterations through this node in which this
+// node's player took the action with the passed key, at any point after this
+// node.
+func (node Node) AMAFVisits(key Key) int64 {
+	if stats := node.amaf.get(key); stats != nil {
+		return stats.visits
+	}
+	return 0
+}
+
+// AMAFScore returns the total score of the specified player over the iterations
+// counted by AMAFVisits.
+func (node Node) AMAFScore(key Key, player uint) float64 {
+	if stats := node.amaf.get(key); stats != nil {
+		return stats.score[player]
+	}
+	return 0
+}
+
+// addAMAF adds a score for the specified player to the all-moves-as-first
+// statistics of each action played by this node's player, counting each action
+// only once.
+func (node *Node) addAMAF(played []PlayedAction, player uint, score float64) {
+	if node.amaf == nil {
+		node.amaf = &amafTable{actions: make(map[Key]*amafStats)}
+	}
+	seen := make(map[Key]bool, len(played))
+	for _, a := range played {
+		if a.Player != node.Player() || seen[a.Key] {
+			continue
+		}
+		seen[a.Key] = true
+		stats, ok := node.amaf.actions[a.Key]
+		if !ok {
+			stats = &amafStats{score: make([]float64, node.NumPlayers())}
+			node.amaf.actions[a.Key] = stats
+		}
+		stats.score[player] += score
+		stats.visits++
+	}
+}
+
+// get returns the statistics of the action with the passed key, or nil if
+// there are none.
+func (t *amafTable) get(key Key) *amafStats {
+	if t == nil {
+		return nil
+	}
+	return t.actions[key]
+}
+
+// copy returns an independent copy of the table.
+func (t *amafTable) copy() *amafTable {
+	return (*amafTable)(nil).merge(t)
+}
+
+// merge adds the statistics of other to those of this table, and returns the
+// result, which is a new table if this one is nil.
+func (t *amafTable) merge(other *amafTable) *amafTable {
+	if other == nil || len(other.actions) == 0 {
+		return t
+	}
+	if t == nil {
+		t = &amafTable{actions: make(map[Key]*amafStats, len(other.actions))}
+	}
+	for k, o := range other.actions {
+		stats, ok := t.actions[k]
+		if !ok {
+			stats = &amafStats{score: make([]float64, len(o.score))}
+			t.actions[k] = stats
+		}
+		for p := range stats.score {
+			if p < len(o.score) {
+				stats.score[p] += o.score[p]
+			}
+		}
+		stats.visits += o.visits
+	}
+	return t
+}
@@ -0,0 +1,160 @@
+package montecarlo
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ActionStats are the statistics of one player's action at a simultaneous-move
+// node (see SimultaneousPolicy).
+type ActionStats struct {
+	// Key is the key of the player's action, see SimultaneousState.
+	Key Key
+	// Visits is the number of times the player chose the action.
+	Visits int64
+	// Score is the player's total final score after choosing the action.
+	Score float64
+	// Estimate is kept by the Bandit choosing between the actions, such as the
+	// estimated total score (EXP3) or the cumulative regret (RegretMatching).
+	Estimate float64
+}
+
+// Bandit is a strategy for one player to choose between their actions at a
+// simultaneous-move node, based only on their own statistics.
+type Bandit interface {
+	// Probabilities returns the probability of choosing each action, given
+	// their statistics and the number of visits of the node.
+	Probabilities(stats []ActionStats, visits int64, explorationParam float64) []float64
+	// Update updates the estimate of each action once the chosen action has
+	// led to the passed final score, before its visits and score are updated.
+	Update(stats []ActionStats, chosen int, score float64)
+}
+
+// DecoupledUCB chooses the action with the highest UCB of each player's own
+// statistics (Decoupled UCT), untried actions are chosen first.
+type DecoupledUCB struct{}
+
+// Probabilities implements Bandit, giving every action with the highest UCB the
+// same probability.
+func (b DecoupledUCB) Probabilities(stats []ActionStats, visits int64, explorationParam float64) []float64 {
+	expl := math.Max(explorationParam, 0)
+	values := make([]float64, len(stats))
+	for i, s := range stats {
+		if s.Visits <= 0 {
+			values[i] = math.Inf(1)
+			continue
+		}
+		n := float64(s.Visits)
+		values[i] = s.Score/n + expl*math.Sqrt(float64(2)*math.Log(float64(visits))/n)
+	}
+	max := math.Inf(-1)
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	probabilities := make([]float64, len(stats))
+	count := 0
+	for i, v := range values {
+		if closeEnough(v, max) {
+			probabilities[i] = 1
+			count++
+		}
+	}
+	for i := range probabilities {
+		probabilities[i] /= float64(count)
+	}
+	return probabilities
+}
+
+// Update implements Bandit, UCB keeps no estimates.
+func (b DecoupledUCB) Update(stats []ActionStats, chosen int, score float64) {}
+
+// EXP3 chooses actions by the exponential-weight algorithm for exploration and
+// exploitation (Auer et al. 2002: The Nonstochastic Multiarmed Bandit Problem -
+// SIAM Journal on Computing, vol. 32, no. 1). Gamma, in (0, 1], is the
+// probability of exploring uniformly. Final scores should lie in [0, 1].
+type EXP3 struct {
+	Gamma float64
+}
+
+// Probabilities implements Bandit.
+func (b EXP3) Probabilities(stats []ActionStats, visits int64, explorationParam float64) []float64 {
+	k := float64(len(stats))
+	eta := b.Gamma / k
+	// subtract the largest estimate, to keep the weights finite
+	max := math.Inf(-1)
+	for _, s := range stats {
+		max = math.Max(max, s.Estimate)
+	}
+	weights := make([]float64, len(stats))
+	total := float64(0)
+	for i, s := range stats {
+		weights[i] = math.Exp(eta * (s.Estimate - max))
+		total += weights[i]
+	}
+	for i := range weights {
+		weights[i] = (1-b.Gamma)*weights[i]/total + b.Gamma/k
+	}
+	return weights
+}
+
+// Update implements Bandit, adding the importance weighted score to the
+// estimate of the chosen action.
+func (b EXP3) Update(stats []ActionStats, chosen int, score float64) {
+	p := b.Probabilities(stats, 0, 0)[chosen]
+	stats[chosen].Estimate += score / p
+}
+
+// RegretMatching chooses actions in proportion to their positive cumulative
+// regret (Hart & Mas-Colell 2000: A Simple Adaptive Procedure Leading to
+// Correlated Equilibrium - Econometrica, vol. 68, no. 5), estimated from the
+// chosen action alone. Gamma, in [0, 1], is the probability of exploring
+// uniformly.
+type RegretMatching struct {
+	Gamma float64
+}
+
+// Probabilities implements Bandit.
+func (b RegretMatching) Probabilities(stats []ActionStats, visits int64, explorationParam float64) []float64 {
+	k := float64(len(stats))
+	probabilities := make([]float64, len(stats))
+	total := float64(0)
+	for _, s := range stats {
+		total += math.Max(s.Estimate, 0)
+	}
+	for i, s := range stats {
+		p := 1 / k
+		if total > 0 {
+			p = math.Max(s.Estimate, 0) / total
+		}
+		probabilities[i] = (1-b.Gamma)*p + b.Gamma/k
+	}
+	return probabilities
+}
+
+// Update implements Bandit, adding the regret of not having chosen each action
+// to its estimate, with the score of the chosen action importance weighted.
+func (b RegretMatching) Update(stats []ActionStats, chosen int, score float64) {
+	p := b.Probabilities(stats, 0, 0)[chosen]
+	for i := range stats {
+		stats[i].Estimate -= score
+	}
+	stats[chosen].Estimate += score / p
+}
+
+// sampleIndex picks an index according to the passed probabilities, using the
+// passed random source.
+func sampleIndex(r *rand.Rand, probabilities []float64) int {
+	total := float64(0)
+	for _, p := range probabilities {
+		total += p
+	}
+	target := r.Float64() * total
+	cumulative := float64(0)
+	for i, p := range probabilities {
+		cumulative += p
+		if target < cumulative {
+			return i
+		}
+	}
+	return len(probabilities) - 1
+}
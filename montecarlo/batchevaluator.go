@@ -0,0 +1,129 @@
+package montecarlo
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchPriorEvaluator is a PriorEvaluator that can evaluate many states in a
+// single call, such as a model which is cheaper to run on a batch of inputs.
+type BatchPriorEvaluator interface {
+	// EvaluateBatch returns the priors and value of each passed state, in the
+	// same order as the states.
+	EvaluateBatch(states []State) (priors []map[Key]float64, values []float64)
+}
+
+// BatchedEvaluator is a PriorEvaluator that collects evaluations requested by
+// concurrent goroutines into batches for a BatchPriorEvaluator. Each call to
+// Evaluate waits until its batch has been evaluated. A batch is evaluated once
+// it holds Size states, or Wait after its first state was added, whichever
+// comes first.
+//
+// Batches are never evaluated concurrently, so the underlying evaluator does
+// not need to be safe for concurrent use. BatchedEvaluator is meant for use
+// with concurrent searches (see TreeParallelSearch); in a single-threaded
+// search every evaluation waits the full Wait duration.
+type BatchedEvaluator struct {
+	evaluator BatchPriorEvaluator
+	size      int
+	wait      time.Duration
+
+	lock sync.Mutex
+	// pending holds the requests of the batch being collected
+	pending []*evaluationRequest
+	// batch counts collected batches, so that a timer only flushes its own
+	batch uint64
+	timer *time.Timer
+	// evalLock serialises calls to the underlying evaluator
+	evalLock sync.Mutex
+}
+
+// evaluationRequest is a single state waiting in a batch.
+type evaluationRequest struct {
+	state  State
+	priors map[Key]float64
+	value  float64
+	done   chan struct{}
+}
+
+// NewBatchedEvaluator constructs a BatchedEvaluator. A size of less than one
+// is treated as one.
+func NewBatchedEvaluator(evaluator BatchPriorEvaluator, size int, wait time.Duration) *BatchedEvaluator {
+	if size < 1 {
+		size = 1
+	}
+	return &BatchedEvaluator{
+		evaluator: evaluator,
+		size:      size,
+		wait:      wait,
+	}
+}
+
+// Evaluate implements PriorEvaluator, waiting for the passed state to be
+// evaluated as part of a batch.
+func (b *BatchedEvaluator) Evaluate(state State) (map[Key]float64, float64) {
+	req := &evaluationRequest{
+		state: state,
+		done:  make(chan struct{}),
+	}
+	b.lock.Lock()
+	b.pending = append(b.pending, req)
+	if len(b.pending) >= b.size {
+		batch := b.take()
+		b.lock.Unlock()
+		b.flush(batch)
+	} else {
+		if len(b.pending) == 1 {
+			batch := b.batch
+			b.timer = time.AfterFunc(b.wait, func() {
+				b.lock.Lock()
+				if b.batch != batch {
+					// the batch was already flushed by size
+					b.lock.Unlock()
+					return
+				}
+				pending := b.take()
+				b.lock.Unlock()
+				b.flush(pending)
+			})
+		}
+		b.lock.Unlock()
+	}
+	<-req.done
+	return req.priors, req.value
+}
+
+// take removes and returns the batch being collected; b.lock must be held.
+func (b *BatchedEvaluator) take() []*evaluationRequest {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	pending := b.pending
+	b.pending = nil
+	b.batch++
+	return pending
+}
+
+// flush evaluates a batch, handing the results back to each request.
+func (b *BatchedEvaluator) flush(batch []*evaluationRequest) {
+	if len(batch) == 0 {
+		return
+	}
+	states := make([]State, len(batch))
+	for i, req := range batch {
+		states[i] = req.state
+	}
+	b.evalLock.Lock()
+	priors, values := b.evaluator.EvaluateBatch(states)
+	b.evalLock.Unlock()
+	for i, req := range batch {
+		if i < len(priors) {
+			req.priors = priors[i]
+		}
+		if i < len(values) {
+			req.value = values[i]
+		}
+		close(req.done)
+	}
+}
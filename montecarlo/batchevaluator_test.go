@@ -0,0 +1,120 @@
+package montecarlo_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// stubBatchEvaluator gives uniform priors and a value of 0.5, recording the
+// size of every batch it is given
+type stubBatchEvaluator struct {
+	lock    sync.Mutex
+	batches []int
+}
+
+func (e *stubBatchEvaluator) EvaluateBatch(states []montecarlo.State) ([]map[montecarlo.Key]float64, []float64) {
+	e.lock.Lock()
+	e.batches = append(e.batches, len(states))
+	e.lock.Unlock()
+	priors := make([]map[montecarlo.Key]float64, len(states))
+	values := make([]float64, len(states))
+	for i, state := range states {
+		legal := state.LegalActions()
+		priors[i] = make(map[montecarlo.Key]float64, len(legal))
+		for k := range legal {
+			priors[i][k] = 1 / float64(len(legal))
+		}
+		values[i] = 0.5
+	}
+	return priors, values
+}
+
+func (e *stubBatchEvaluator) total() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	total := 0
+	for _, n := range e.batches {
+		total += n
+	}
+	return total
+}
+
+func TestBatchedEvaluatorFlushesOnSize(t *testing.T) {
+	stub := &stubBatchEvaluator{}
+	// a long wait, so only the size threshold can flush
+	evaluator := montecarlo.NewBatchedEvaluator(stub, 4, time.Hour)
+	var wg sync.WaitGroup
+	wg.Add(8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			defer wg.Done()
+			priors, value := evaluator.Evaluate(initState())
+			assert.Equal(t, 9, len(priors))
+			assert.Equal(t, 0.5, value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []int{4, 4}, stub.batches)
+}
+
+func TestBatchedEvaluatorFlushesOnTime(t *testing.T) {
+	stub := &stubBatchEvaluator{}
+	evaluator := montecarlo.NewBatchedEvaluator(stub, 64, 10*time.Millisecond)
+	start := time.Now()
+	_, value := evaluator.Evaluate(initState())
+	assert.Equal(t, 0.5, value)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	assert.Equal(t, []int{1}, stub.batches)
+}
+
+func TestBatchedEvaluatorTreeParallel(t *testing.T) {
+	makeActions()
+	stub := &stubBatchEvaluator{}
+	statePolicy = montecarlo.NewPUCTPolicy(montecarlo.NewBatchedEvaluator(stub, 4, time.Millisecond), true)
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.TreeParallelSearch(4, 50, 1.5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal)
+	assert.Equal(t, int64(4*50), ai.Tree().Root().Visits())
+	for _, n := range stub.batches {
+		assert.True(t, n >= 1 && n <= 4, "batches should never exceed the size threshold")
+	}
+	// every visited node is evaluated exactly once
+	assert.Equal(t, countEvaluated(ai.Tree().Root()), stub.total())
+}
+
+func TestBatchedEvaluatorBatchesTreeParallel(t *testing.T) {
+	makeActions()
+	stub := &stubBatchEvaluator{}
+	// a wait long enough that batches are only flushed by time when there
+	// are no more simulations to fill them
+	statePolicy = montecarlo.NewPUCTPolicy(montecarlo.NewBatchedEvaluator(stub, 4, 50*time.Millisecond), true)
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ai.TreeParallelSearch(4, 25, 1.5, 3); err != nil {
+		t.Fatal(err)
+	}
+	// evaluations happen outside of the tree's lock, so the simulations of
+	// every worker are batched together
+	several := 0
+	for _, n := range stub.batches {
+		if n > 1 {
+			several++
+		}
+	}
+	assert.True(t, several > len(stub.batches)/2, "most batches should hold several states: %v", stub.batches)
+}
@@ -0,0 +1,287 @@
+package montecarlo
+
+import "math"
+
+// ScoreBoundedPolicy is the UCTPolicy extended with Score Bounded MCTS
+// (Cazenave & Saffidine 2010: Score Bounded Monte-Carlo Tree Search - Computers
+// and Games, LNCS vol. 6515), for games whose final scores take more than two
+// values, such as games with draws.
+//
+// Every node keeps a pessimistic and an optimistic bound on the final score of
+// each player, between Min and Max. The bounds of terminal nodes are exactly
+// their scores, and are propagated up the tree during backpropagation: the
+// player to move at a node can do no worse than the best pessimistic bound of
+// its children, nor better than their best optimistic bound. Children which
+// cannot improve on the pessimistic bound of their parent's player are pruned
+// from selection, and the search stops once the root's score is exactly known.
+// The bounds of chance nodes are those of their outcomes, weighted by
+// probability.
+type ScoreBoundedPolicy struct {
+	Min float64
+	Max float64
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
+}
+
+// NewScoreBoundedPolicy constructs a score bounded policy for games whose
+// scores lie between min and max.
+func NewScoreBoundedPolicy(min, max float64) ScoreBoundedPolicy {
+	return ScoreBoundedPolicy{
+		Min: min,
+		Max: max,
+	}
+}
+
+// Bounds returns the pessimistic and optimistic bounds on the final score of
+// each player from the state of this node. Both are nil if the node's bounds
+// are not tracked (see ScoreBoundedPolicy).
+func (node Node) Bounds() (pessimistic, optimistic []float64) {
+	if node.bounds == nil {
+		return nil, nil
+	}
+	return node.bounds.pess, node.bounds.opt
+}
+
+// Exact returns the exact final score of each player from the state of this
+// node, if it has been proven by its bounds, or nil otherwise.
+func (node Node) Exact() []float64 {
+	if !node.exact() {
+		return nil
+	}
+	return append([]float64(nil), node.bounds.pess...)
+}
+
+// exact returns true if the bounds of this node are equal for every player.
+func (node Node) exact() bool {
+	if node.bounds == nil {
+		return false
+	}
+	for p := range node.bounds.pess {
+		if !closeEnough(node.bounds.pess[p], node.bounds.opt[p]) {
+			return false
+		}
+	}
+	return true
+}
+
+// solved returns true if this node is proven, either by MCTS-Solver or by its
+// score bounds.
+func (node Node) solved() bool {
+	return node.proven() || node.exact()
+}
+
+// boundsWithin returns the bounds of this node, or min and max for every
+// player if they are not yet tracked.
+func (node *Node) boundsWithin(min, max float64) ([]float64, []float64) {
+	if node.bounds != nil {
+		return node.bounds.pess, node.bounds.opt
+	}
+	return unknownBounds(node.NumPlayers(), min, max)
+}
+
+// unknownBounds returns the bounds of a node about which nothing is known yet.
+func unknownBounds(numPlayers uint, min, max float64) ([]float64, []float64) {
+	pess := make([]float64, numPlayers)
+	opt := make([]float64, numPlayers)
+	for p := range pess {
+		pess[p], opt[p] = min, max
+	}
+	return pess, opt
+}
+
+// updateBounds recomputes the bounds of this node from its state if it is
+// terminal, or from its children otherwise. The other players' bounds only
+// consider the children that the player to move could still choose.
+func (node *Node) updateBounds(min, max float64) {
+	numPlayers := node.NumPlayers()
+	pess := make([]float64, numPlayers)
+	opt := make([]float64, numPlayers)
+	if node.IsTerminal() {
+		if node.State == nil {
+			return
+		}
+		for p := range pess {
+			pess[p] = node.State.Score(uint(p))
+			opt[p] = pess[p]
+		}
+		node.bounds = &scoreBounds{pess, opt}
+		return
+	}
+	if outcomes := node.outcomes(); outcomes != nil {
+		pess, opt = node.expectedBounds(outcomes, min, max)
+		node.bounds = &scoreBounds{pess, opt}
+		return
+	}
+	player := node.Player()
+	exhausted := node.IsExhausted()
+	keys := sortedChildKeys(node.children)
+	// the player to move picks the child that is best for them, untried
+	// actions could lead to any score
+	pess[player], opt[player] = min, max
+	if exhausted {
+		opt[player] = math.Inf(-1)
+	}
+	for _, k := range keys {
+		cp, co := node.children[k].boundsWithin(min, max)
+		pess[player] = math.Max(pess[player], cp[player])
+		if exhausted {
+			opt[player] = math.Max(opt[player], co[player])
+		}
+	}
+	for p := range pess {
+		if uint(p) == player {
+			continue
+		}
+		pess[p], opt[p] = math.Inf(1), math.Inf(-1)
+		if !exhausted {
+			pess[p], opt[p] = min, max
+		}
+	}
+	for _, k := range keys {
+		cp, co := node.children[k].boundsWithin(min, max)
+		// only children which might be chosen by the player to move
+		if co[player] <= pess[player] && !closeEnough(cp[player], pess[player]) {
+			continue
+		}
+		for p := range pess {
+			if uint(p) == player {
+				continue
+			}
+			pess[p] = math.Min(pess[p], cp[p])
+			opt[p] = math.Max(opt[p], co[p])
+		}
+	}
+	node.bounds = &scoreBounds{pess, opt}
+}
+
+// expectedBounds gives the bounds of a chance node with the passed outcomes:
+// the bounds of each outcome weighted by its probability, where outcomes which
+// have not been tried could lead to any score.
+func (node *Node) expectedBounds(outcomes map[Key]float64, min, max float64) ([]float64, []float64) {
+	pess := make([]float64, node.NumPlayers())
+	opt := make([]float64, node.NumPlayers())
+	total := float64(0)
+	for _, p := range outcomes {
+		total += p
+	}
+	for _, k := range sortedOutcomeKeys(outcomes) {
+		weight := outcomes[k] / total
+		cp, co := unknownBounds(node.NumPlayers(), min, max)
+		if child := node.GetChild(k); child != nil {
+			cp, co = child.boundsWithin(min, max)
+		}
+		for p := range pess {
+			pess[p] += weight * cp[p]
+			opt[p] += weight * co[p]
+		}
+	}
+	return pess, opt
+}
+
+// scoreBounds are the pessimistic and optimistic bounds on each player's final
+// score from the state of a node, see ScoreBoundedPolicy.
+type scoreBounds struct {
+	pess []float64
+	opt  []float64
+}
+
+// copy returns an independent copy of the bounds.
+func (b *scoreBounds) copy() *scoreBounds {
+	if b == nil {
+		return nil
+	}
+	return &scoreBounds{
+		pess: append([]float64(nil), b.pess...),
+		opt:  append([]float64(nil), b.opt...),
+	}
+}
+
+// merge narrows these bounds to those of other, and returns the result, which
+// is a copy of other if these are nil.
+func (b *scoreBounds) merge(other *scoreBounds) *scoreBounds {
+	if b == nil {
+		return other.copy()
+	}
+	if other == nil {
+		return b
+	}
+	for p := range b.pess {
+		b.pess[p] = math.Max(b.pess[p], other.pess[p])
+		b.opt[p] = math.Min(b.opt[p], other.opt[p])
+	}
+	return b
+}
+
+// pruned returns true if the passed child of this node cannot improve on the
+// pessimistic bound of this node's player, or is already solved.
+func (node *Node) pruned(child *Node) bool {
+	if child.solved() {
+		return true
+	}
+	if node.bounds == nil || child.bounds == nil {
+		return false
+	}
+	player := node.Player()
+	return child.bounds.opt[player] <= node.bounds.pess[player]
+}
+
+/******** IMPLEMENT Policy ********/
+
+// Select selects the unpruned child with the highest UCB, until a node that is
+// not fully expanded, or a solved node, is found. Pruned children are only
+// selected when every child is pruned.
+func (p ScoreBoundedPolicy) Select(node *Node, explorationParam float64) *Node {
+	n := node
+	for n != nil && (n.IsRoot() || !n.IsTerminal()) && !n.solved() {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if !n.IsExhausted() {
+			return p.Expand(n, explorationParam)
+		}
+		if n.IsLeaf() {
+			break
+		}
+		parent := n
+		_, n = parent.selectChildBy(func(child *Node) float64 {
+			if parent.pruned(child) {
+				return math.Inf(-1)
+			}
+			return parent.childValue(child, explorationParam)
+		})
+	}
+	return n
+}
+
+// Expand acts in exactly the same way as the UCTPolicy
+func (p ScoreBoundedPolicy) Expand(node *Node, explorationParam float64) *Node {
+	return UCTPolicy{}.Expand(node, explorationParam)
+}
+
+// Simulate acts in exactly the same way as the UCTPolicy
+func (p ScoreBoundedPolicy) Simulate(node *Node) float64 {
+	return UCTPolicy{Playout: p.Playout}.Simulate(node)
+}
+
+// Backpropagate acts in exactly the same way as the UCTPolicy, then updates
+// the bounds of every node on the way to the root.
+func (p ScoreBoundedPolicy) Backpropagate(node *Node, score float64) {
+	p.BackpropagateN(node, score, 1)
+}
+
+// BackpropagateN acts in exactly the same way as the UCTPolicy, then updates
+// the bounds of every node on the way to the root.
+func (p ScoreBoundedPolicy) BackpropagateN(node *Node, score float64, visits int64) {
+	p.backpropagatePath(parentPath(node), Result{Score: score}, visits)
+}
+
+func (p ScoreBoundedPolicy) backpropagatePath(path path, result Result, visits int64) {
+	UCTPolicy{}.backpropagatePath(path, result, visits)
+	for _, e := range path {
+		e.node.updateBounds(p.Min, p.Max)
+	}
+}
@@ -0,0 +1,65 @@
+package montecarlo_test
+
+import (
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestScoreBoundedFindsDraw(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewScoreBoundedPolicy(0, 1)
+	defer func() { statePolicy = nil }()
+	// O can only draw by blocking the left column
+	state := boardState(false,
+		"XOX",
+		"XOO",
+		".X.",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(100, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, montecarlo.Key("SET_O_2_0"), result.BestKey)
+	assert.Equal(t, []float64{0.5, 0.5}, result.Exact)
+	for _, c := range result.Children {
+		switch c.Key {
+		case "SET_O_2_0":
+			assert.Equal(t, []float64{0.5, 0.5}, c.Exact)
+		case "SET_O_2_2":
+			assert.Equal(t, []float64{1, 0}, c.Exact)
+		}
+	}
+	// a draw cannot be proven by MCTS-Solver alone
+	_, proven := ai.Tree().Root().ProvenWinner()
+	assert.False(t, proven)
+}
+
+func TestScoreBoundedSolvesOpening(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewScoreBoundedPolicy(0, 1)
+	defer func() { statePolicy = nil }()
+	state := boardState(true,
+		"X..",
+		".O.",
+		"...",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(20000, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float64{0.5, 0.5}, result.Exact)
+	// pruning solves the position without searching every line
+	assert.True(t, result.Iterations < 20000)
+	pess, opt := ai.Tree().Root().Bounds()
+	assert.Equal(t, pess, opt)
+}
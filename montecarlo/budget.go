@@ -0,0 +1,38 @@
+package montecarlo
+
+import "time"
+
+// Budget limits how much a search may do before it stops. Any combination of
+// limits may be set, the search stops as soon as any one of them is reached. A
+// zero field places no limit, so a search with a zero Budget only stops when
+// its context is done.
+type Budget struct {
+	// Iterations is the maximum number of iterations to run.
+	Iterations int64
+	// Duration is the maximum wall-clock time to search for.
+	Duration time.Duration
+	// Deadline is the time by which the search must stop.
+	Deadline time.Time
+	// Nodes is the maximum number of nodes the tree may grow to.
+	Nodes int
+}
+
+// deadline returns the earliest time at which a search started at the passed
+// time must stop, if the budget places any limit on time.
+func (b Budget) deadline(start time.Time) (time.Time, bool) {
+	deadline := b.Deadline
+	if b.Duration > 0 {
+		if d := start.Add(b.Duration); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// exhausted returns true if a search that has run the passed number of
+// iterations, over a tree of the passed number of nodes, has used up the
+// budget.
+func (b Budget) exhausted(iterations int64, nodes int) bool {
+	return (b.Iterations > 0 && iterations >= b.Iterations) ||
+		(b.Nodes > 0 && nodes >= b.Nodes)
+}
@@ -0,0 +1,135 @@
+package montecarlo
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ChanceState is a State which may be a chance state, where the action taken
+// is decided by the environment (such as the roll of a die) rather than by a
+// player.
+type ChanceState interface {
+	State
+	// Outcomes returns the probability of each legal action being taken from
+	// this state, by key, if it is a chance state; nil otherwise.
+	Outcomes() map[Key]float64
+}
+
+// outcomes returns the probability of each legal action being taken from this
+// node if it is a chance node, or nil otherwise. A node is a chance node if its
+// state is a chance state, or if its policy is a DeterminizationPolicy.
+func (node Node) outcomes() map[Key]float64 {
+	var probabilities map[Key]float64
+	if cs, ok := node.State.(ChanceState); ok {
+		probabilities = cs.Outcomes()
+	}
+	if dp, ok := node.policy.(DeterminizationPolicy); ok && len(probabilities) == 0 {
+		probabilities = dp.childProbability
+	}
+	if len(probabilities) == 0 || node.State == nil {
+		return nil
+	}
+	legalActions := node.State.LegalActions()
+	outcomes := make(map[Key]float64, len(probabilities))
+	for k, p := range probabilities {
+		if _, ok := legalActions[k]; ok && p > 0 {
+			outcomes[k] = p
+		}
+	}
+	if len(outcomes) == 0 {
+		return nil
+	}
+	return outcomes
+}
+
+// IsChance returns true if this node is a chance node, see ChanceState.
+func (node Node) IsChance() bool {
+	return node.outcomes() != nil
+}
+
+// sampleOutcome picks a key from outcomes according to its probability, using
+// the passed random source. Probabilities are normalised by their total.
+func sampleOutcome(r *rand.Rand, outcomes map[Key]float64) Key {
+	pairs := make(probabilityPairList, 0, len(outcomes))
+	total := float64(0)
+	for _, k := range sortedOutcomeKeys(outcomes) {
+		pairs = append(pairs, probabilityPair{
+			probability: outcomes[k],
+			action:      k,
+		})
+		total += outcomes[k]
+	}
+	if total > 1+0.000001 {
+		log.Warnf("probabilities of outcomes %v are cumulatively more probable than 1!", outcomes)
+	}
+	// sort the list of pairs by their probability (lowest first)
+	sort.Stable(pairs)
+	target := r.Float64() * total
+	cProbability := float64(0)
+	for _, v := range pairs {
+		cProbability += v.probability
+		if target < cProbability {
+			return v.action
+		}
+	}
+	return pairs[len(pairs)-1].action
+}
+
+// sortedOutcomeKeys returns the keys of outcomes in order.
+func sortedOutcomeKeys(outcomes map[Key]float64) keyList {
+	keys := make(keyList, 0, len(outcomes))
+	for k := range outcomes {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// selectOutcome samples an outcome of this node by its probability, if this is
+// a chance node, and returns the child for it. If the child did not exist it
+// is created, and expanded is true. A nil child is returned if this is not a
+// chance node.
+func (node *Node) selectOutcome() (child *Node, expanded bool) {
+	outcomes := node.outcomes()
+	if outcomes == nil {
+		return nil, false
+	}
+	k := sampleOutcome(node.Rand(), outcomes)
+	if child := node.GetChild(k); child != nil {
+		node.table().reached(node, k, child)
+		return child, false
+	}
+	n, err := NewNode(node.NumPlayers())
+	if err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+	n.State = node.State.LegalActions()[k](node.State.Copy())
+	n.policy = n.State.Policy()
+	return node.SetChild(k, &n), true
+}
+
+// expectedScore gives the mean score of this node for the passed player. For
+// chance nodes this is the mean score of each visited outcome weighted by its
+// probability, rather than by how often it happened to be visited.
+func (node Node) expectedScore(player uint) float64 {
+	if outcomes := node.outcomes(); outcomes != nil {
+		sum, total := float64(0), float64(0)
+		for _, k := range sortedChildKeys(node.children) {
+			child := node.children[k]
+			if p := outcomes[k]; p > 0 && child.Visits() > 0 {
+				sum += p * child.expectedScore(player)
+				total += p
+			}
+		}
+		if total > 0 {
+			return sum / total
+		}
+	}
+	if node.Visits() <= 0 {
+		return 0
+	}
+	return node.Score(player) / float64(node.Visits())
+}
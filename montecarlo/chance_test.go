@@ -0,0 +1,70 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestChanceNodeSearch(t *testing.T) {
+	for _, viaPolicy := range []bool{false, true} {
+		game := gambleGame(viaPolicy)
+		mcts, err := NewMultiplayerMCTS(1, game.state(), game.actions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mcts.SetRand(rand.New(rand.NewSource(1)))
+		result, err := mcts.Search(2000, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, Key("safe"), result.BestKey)
+		coin := mcts.Tree().Root().GetChild("gamble")
+		assert.True(t, coin.IsChance())
+		// outcomes are sampled by probability
+		win := float64(coin.GetChild("win").Visits()) / float64(coin.Visits())
+		assert.InDelta(t, 0.3, win, 0.1)
+		for _, c := range result.Children {
+			if c.Key == "gamble" {
+				assert.InDelta(t, 0.3, c.MeanScore[0], 0.000001)
+			}
+		}
+	}
+}
+
+func TestExpectedScoreWeightsOutcomes(t *testing.T) {
+	game := gambleGame(false)
+	actions := game.actions()
+	coin, _ := NewNode(1)
+	coin.State = game.state("gamble")
+	// the unlikely outcome happened to be visited far more often
+	for k, visits := range map[Key]int64{"win": 9, "lose": 1} {
+		child, _ := NewNode(1)
+		child.State = actions[k](coin.State)
+		child.AddVisits(visits)
+		child.SetScore(0, child.State.Score(0)*float64(visits))
+		coin.SetChild(k, &child)
+		coin.AddVisits(visits)
+		coin.SetScore(0, coin.Score(0)+child.Score(0))
+	}
+	assert.InDelta(t, 0.9, coin.Score(0)/float64(coin.Visits()), 0.000001)
+	assert.InDelta(t, 0.3, coin.expectedScore(0), 0.000001)
+}
+
+func TestDeterminizationPolicySelect(t *testing.T) {
+	root, _ := NewNode(1)
+	root.State = gambleGame(true).state("gamble")
+	root.policy = root.State.Policy()
+	root.rand = rand.New(rand.NewSource(1))
+	counts := make(map[Key]int)
+	for i := 0; i < 1000; i++ {
+		n := root.Policy().Select(&root, 1)
+		if assert.NotNil(t, n) {
+			counts[n.Key()]++
+		}
+	}
+	assert.Equal(t, 2, len(root.children))
+	assert.InDelta(t, 300, float64(counts["win"]), 60)
+	assert.InDelta(t, 700, float64(counts["lose"]), 60)
+}
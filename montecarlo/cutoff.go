@@ -0,0 +1,147 @@
+package montecarlo
+
+import "math/rand"
+
+// Evaluator is a State with a static evaluation function, with which playouts
+// can be ended early (see CutoffPlayout).
+type Evaluator interface {
+	State
+	// Evaluate returns a heuristic estimate of the final score of every
+	// player, indexed by player, on the same scale as Score.
+	Evaluate() []float64
+}
+
+// PlayoutCutoff is a PlayoutPolicy which can end playouts early, such as
+// CutoffPlayout. Policies choosing by another policy, such as LGRF, pass Cutoff
+// on to it.
+type PlayoutCutoff interface {
+	PlayoutPolicy
+	// Cutoff returns the final score of every player, indexed by player, if
+	// the playout should end at the passed state, reached by taking depth
+	// actions, or false if it should go on.
+	Cutoff(state State, depth int) ([]float64, bool)
+}
+
+// CutoffPlayout ends playouts early, once Depth actions have been taken (a
+// Depth that is not positive never ends them), or, if UseThresholds is true,
+// as soon as the evaluation of any player is at least Win or at most Loss.
+// The state reached is then evaluated, and the evaluation is used as the final
+// score of every player. Playouts from states which are not Evaluators are
+// never ended by this policy.
+//
+// Actions are chosen by Playout, or uniformly at random if it is nil. Adaptive
+// playout policies, such as MAST, learn from the evaluation of the state
+// reached.
+type CutoffPlayout struct {
+	Playout       PlayoutPolicy
+	Depth         int
+	UseThresholds bool
+	Win           float64
+	Loss          float64
+}
+
+// NewCutoffPlayout constructs a playout policy ending playouts after depth
+// actions, choosing actions by the passed playout policy.
+func NewCutoffPlayout(depth int, playout PlayoutPolicy) CutoffPlayout {
+	return CutoffPlayout{
+		Playout: playout,
+		Depth:   depth,
+	}
+}
+
+// NewThresholdCutoffPlayout constructs a playout policy ending playouts after
+// depth actions, or as soon as the evaluation of any player reaches the win or
+// loss threshold, choosing actions by the passed playout policy.
+func NewThresholdCutoffPlayout(depth int, win, loss float64, playout PlayoutPolicy) CutoffPlayout {
+	return CutoffPlayout{
+		Playout:       playout,
+		Depth:         depth,
+		UseThresholds: true,
+		Win:           win,
+		Loss:          loss,
+	}
+}
+
+// playout returns the policy choosing actions, UniformPlayout by default.
+func (pp CutoffPlayout) playout() PlayoutPolicy {
+	if pp.Playout == nil {
+		return UniformPlayout{}
+	}
+	return pp.Playout
+}
+
+// Choose implements PlayoutPolicy, choosing by the Playout policy.
+func (pp CutoffPlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	return pp.playout().Choose(state, legalActions, r)
+}
+
+func (pp CutoffPlayout) chooseWith(stats *playoutStats, history []PlayedAction, state State, legalActions ActionSet, r *rand.Rand) Key {
+	if adaptive, ok := pp.playout().(adaptivePlayout); ok {
+		return adaptive.chooseWith(stats, history, state, legalActions, r)
+	}
+	return pp.playout().Choose(state, legalActions, r)
+}
+
+func (pp CutoffPlayout) learn(stats *playoutStats, actions []PlayedAction, final State, numPlayers uint) {
+	if adaptive, ok := pp.playout().(adaptivePlayout); ok {
+		adaptive.learn(stats, actions, final, numPlayers)
+	}
+}
+
+// Cutoff implements PlayoutCutoff, giving the evaluation of the passed state
+// once the depth or a threshold is reached. Otherwise, it is passed on to the
+// Playout policy.
+func (pp CutoffPlayout) Cutoff(state State, depth int) ([]float64, bool) {
+	evaluator, ok := state.(Evaluator)
+	if !ok {
+		return playoutCutoff(pp.Playout, state, depth)
+	}
+	reached := pp.Depth > 0 && depth >= pp.Depth
+	if !reached && !pp.UseThresholds {
+		return playoutCutoff(pp.Playout, state, depth)
+	}
+	scores := evaluator.Evaluate()
+	if !reached {
+		for _, score := range scores {
+			if score >= pp.Win || score <= pp.Loss {
+				reached = true
+				break
+			}
+		}
+	}
+	if !reached {
+		return playoutCutoff(pp.Playout, state, depth)
+	}
+	return scores, true
+}
+
+// playoutCutoff returns the final score of every player to end a playout with,
+// if the passed policy is a PlayoutCutoff which ends it at the passed state.
+func playoutCutoff(policy PlayoutPolicy, state State, depth int) ([]float64, bool) {
+	if cutoff, ok := policy.(PlayoutCutoff); ok {
+		return cutoff.Cutoff(state, depth)
+	}
+	return nil, false
+}
+
+// evaluatedState is the state a playout was ended in early, scored by its
+// evaluation. No more actions can be taken from it.
+type evaluatedState struct {
+	State
+	scores []float64
+}
+
+func (s evaluatedState) LegalActions() ActionSet {
+	return ActionSet{}
+}
+
+func (s evaluatedState) Score(player uint) float64 {
+	if int(player) >= len(s.scores) {
+		return 0
+	}
+	return s.scores[player]
+}
+
+func (s evaluatedState) Copy() State {
+	return evaluatedState{s.State.Copy(), append([]float64(nil), s.scores...)}
+}
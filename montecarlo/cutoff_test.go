@@ -0,0 +1,87 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// upPlayout always steps up
+type upPlayout struct{}
+
+func (upPlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	return "up"
+}
+
+func walkNode(state State) *Node {
+	node, _ := NewNode(1)
+	node.State = state
+	node.rand = rand.New(rand.NewSource(1))
+	return &node
+}
+
+func TestCutoffPlayoutDepth(t *testing.T) {
+	node := walkNode(walkGame(100, nil).state())
+	state, played := playout(node, NewCutoffPlayout(3, upPlayout{}))
+	assert.Equal(t, 3, len(played))
+	assert.Equal(t, 3, len(toyPosition(state.(evaluatedState).State).moves))
+	// the evaluation is scored, rather than the game's score
+	assert.Equal(t, 0.5+3.0/200, state.Score(0))
+
+	// without a cutoff the whole game is played
+	state, played = playout(node, upPlayout{})
+	assert.Equal(t, 100, len(played))
+	assert.Equal(t, float64(1), state.Score(0))
+}
+
+func TestCutoffPlayoutThresholds(t *testing.T) {
+	node := walkNode(walkGame(100, nil).state())
+	// an evaluation of 0.6 is reached 20 steps up
+	state, played := playout(node, NewThresholdCutoffPlayout(0, 0.6, 0.4, upPlayout{}))
+	assert.Equal(t, 20, len(played))
+	assert.InDelta(t, 0.6, state.Score(0), 0.000001)
+
+	// the depth still applies
+	_, played = playout(node, NewThresholdCutoffPlayout(10, 0.6, 0.4, upPlayout{}))
+	assert.Equal(t, 10, len(played))
+}
+
+func TestCutoffPlayoutWrapped(t *testing.T) {
+	node := walkNode(walkGame(100, nil).state())
+	cutoff := NewCutoffPlayout(3, upPlayout{})
+	for _, policy := range []PlayoutPolicy{
+		&cutoff,
+		NewLGRF(cutoff),
+		NewWinningMovePlayout(1, cutoff),
+		NewCutoffPlayout(0, cutoff),
+	} {
+		state, played := playout(node, policy)
+		assert.Equal(t, 3, len(played), "%T", policy)
+		assert.Equal(t, 0.5+3.0/200, state.Score(0), "%T", policy)
+	}
+}
+
+func TestCutoffPlayoutWithoutEvaluator(t *testing.T) {
+	node := walkNode(pairGame(nil).state())
+	_, played := playout(node, NewCutoffPlayout(1, nil))
+	assert.Equal(t, 2, len(played))
+}
+
+func TestCutoffPlayoutSearch(t *testing.T) {
+	playout := NewCutoffPlayout(5, NewMAST(0.1))
+	game := walkGame(1000, UCTPolicy{Playout: playout})
+	mcts, err := NewMultiplayerMCTS(1, game.state(), game.actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcts.SetRand(rand.New(rand.NewSource(1)))
+	result, err := mcts.Search(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Key("up"), result.BestKey)
+	// adaptive policies only learn from the actions before the cutoff
+	height := mcts.Tree().Root().height()
+	assert.True(t, actionVisits(mcts.Tree().Root().playoutStats()) <= int64(100*(5+height)))
+}
@@ -1,13 +1,5 @@
 package montecarlo
 
-import (
-	"math/rand"
-	"sort"
-	"time"
-
-	log "github.com/Sirupsen/logrus"
-)
-
 // DeterminizationPolicy is a policy - an extension of the UCTPolicy - for the determinization of probabilistic
 // actions - all nodes with this policy must have the ability to determine the
 // probability of an action leading to a child node.
@@ -16,6 +8,8 @@ import (
 // action is selected - and the only determining factor is a distinct probability
 type DeterminizationPolicy struct {
 	childProbability map[Key]float64
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
 }
 
 // NewDeterminizationPolicy constructs a determinization policy for determinizing
@@ -62,45 +56,25 @@ func (ppl probabilityPairList) Less(i, j int) bool {
 
 /*-------- IMPLEMNET Policy --------*/
 
-// Select the key for an action based on it's probability
+// Select a child based on its probability, the child is expanded if it does not
+// exist yet; otherwise selection continues from the child according to its own
+// policy. Nodes with this policy are chance nodes, so their mean score is
+// weighted by the probability of each child (see ChanceState).
 func (dp DeterminizationPolicy) Select(node *Node, expl float64) *Node {
-	if len(node.children) <= 0 && node.IsExhausted() {
-		return nil
-	}
-	// generate all moves
-	for !node.IsExhausted() {
-		node.Policy().Expand(node, expl)
-	}
-	cProbability := float64(0)
-	pairs := make(probabilityPairList, len(node.children))
-	for k := range node.children {
-		pairs = append(pairs, probabilityPair{
-			probability: dp.ChildProbability(k),
-			action:      k,
-		})
-	}
-	// sort the list of pairs by their probability (lowest first)
-	sort.Sort(pairs)
-	// pick a random number as the target (in range [0, 1))
-	rand.Seed(time.Now().UTC().UnixNano())
-	target := rand.Float64()
-	var actionKey *Key
-	actionKey = nil
-	for _, v := range pairs {
-		cProbability += v.probability
-		if float64(target) < cProbability {
-			actionKey = &v.action
-		}
+	child, expanded := node.selectOutcome()
+	if child == nil {
+		// none of the legal actions are probable, or the node is terminal
+		return node
 	}
-	if cProbability > 1 {
-		log.Warnf("probabilities for children of deterministic node %v are cumulatively more probable than 1!", node)
+	if expanded || child.IsTerminal() {
+		return child
 	}
-	return node.GetChild(actionKey)
+	return child.Policy().Select(child, expl)
 }
 
 // Simulate acts in exactly the same way as the UCTPolicy
 func (dp DeterminizationPolicy) Simulate(node *Node) float64 {
-	return UCTPolicy{}.Simulate(node)
+	return UCTPolicy{Playout: dp.Playout}.Simulate(node)
 }
 
 // Expand acts in exactly the same way as the UCTPolicy
@@ -112,3 +86,12 @@ func (dp DeterminizationPolicy) Expand(node *Node, expl float64) *Node {
 func (dp DeterminizationPolicy) Backpropagate(node *Node, score float64) {
 	UCTPolicy{}.Backpropagate(node, score)
 }
+
+// BackpropagateN acts in exactly the same way as the UCTPolicy
+func (dp DeterminizationPolicy) BackpropagateN(node *Node, score float64, visits int64) {
+	UCTPolicy{}.BackpropagateN(node, score, visits)
+}
+
+func (dp DeterminizationPolicy) backpropagatePath(path path, result Result, visits int64) {
+	UCTPolicy{}.backpropagatePath(path, result, visits)
+}
@@ -2,6 +2,7 @@ package montecarlo
 
 import (
 	"fmt"
+	"time"
 )
 
 /*
@@ -33,6 +34,33 @@ type MergeStateMismatch struct {
 	other State
 }
 
+// UnknownJobKind thrown when a worker is given a job of a kind it doesn't know
+// how to carry out.
+type UnknownJobKind JobKind
+
+// RemoteWorkerError thrown when a worker process fails to carry out a job, it
+// contains the worker process's error message.
+type RemoteWorkerError string
+
+// UnevaluatedNode thrown when a PUCTPolicy is asked to expand a node whose
+// state has not been evaluated yet.
+type UnevaluatedNode struct {
+	state State
+}
+
+// WorkerTimeout thrown when a worker process fails to answer a job by its
+// deadline.
+type WorkerTimeout struct {
+	deadline time.Time
+}
+
+// IllegalChildKey thrown when a child is requested (for example by a worker
+// process, or when advancing a tree) whose key is not a legal action from its
+// parent's state.
+type IllegalChildKey struct {
+	key Key
+}
+
 /*
  Implement the Error interface for all the error types.
 */
@@ -52,3 +80,23 @@ func (mpim MergePlayerIndexMismatch) Error() string {
 func (msm MergeStateMismatch) Error() string {
 	return fmt.Sprintf("merge state mismatch: %v vs. %v", msm.one, msm.other)
 }
+
+func (ujk UnknownJobKind) Error() string {
+	return fmt.Sprintf("unknown job kind: %d", int(ujk))
+}
+
+func (rwe RemoteWorkerError) Error() string {
+	return fmt.Sprintf("remote worker: %s", string(rwe))
+}
+
+func (un UnevaluatedNode) Error() string {
+	return fmt.Sprintf("can't expand a node that has not been evaluated: %v", un.state)
+}
+
+func (wt WorkerTimeout) Error() string {
+	return fmt.Sprintf("worker did not answer by %v", wt.deadline)
+}
+
+func (ick IllegalChildKey) Error() string {
+	return fmt.Sprintf("child key is not a legal action: %v", ick.key)
+}
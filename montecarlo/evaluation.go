@@ -0,0 +1,106 @@
+package montecarlo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// evaluation is the result of evaluating a node's state with a PriorEvaluator.
+// A state is evaluated at most once, even when several goroutines try to
+// evaluate it at the same time.
+type evaluation struct {
+	once sync.Once
+	// done is set atomically once priors and value have been written
+	done   int32
+	priors map[Key]float64
+	value  float64
+}
+
+// puctStats are what PUCTPolicy keeps for a node: the prior probability of the
+// action leading to it being chosen, and the evaluation of its state, which is
+// shared with any detached copies of the node.
+type puctStats struct {
+	prior float64
+	eval  *evaluation
+}
+
+// evaluation returns the evaluation of this node's state, creating an empty
+// one if needed.
+func (node *Node) evaluation() *evaluation {
+	if node.puct == nil {
+		node.puct = &puctStats{}
+	}
+	if node.puct.eval == nil {
+		node.puct.eval = &evaluation{}
+	}
+	return node.puct.eval
+}
+
+// evaluated reports whether the state of this node has been evaluated.
+func (node *Node) evaluated() bool {
+	return node.puct != nil && node.puct.eval != nil && node.puct.eval.isDone()
+}
+
+// copy returns an independent copy of the statistics, with a copy of the
+// evaluation if it is finished.
+func (s *puctStats) copy() *puctStats {
+	if s == nil {
+		return nil
+	}
+	return &puctStats{prior: s.prior, eval: s.eval.copy()}
+}
+
+// merge returns these statistics, or a copy of other if there are none, taking
+// the evaluation of other if this state has not been evaluated. Both belong to
+// nodes for the same state, so there is nothing to add up.
+func (s *puctStats) merge(other *puctStats) *puctStats {
+	if s == nil {
+		return other.copy()
+	}
+	if s.eval == nil && other != nil {
+		s.eval = other.eval.copy()
+	}
+	return s
+}
+
+// bare returns the prior alone, which belongs to the action leading to the
+// node rather than to what was learned about it.
+func (s *puctStats) bare() *puctStats {
+	if s == nil {
+		return nil
+	}
+	return &puctStats{prior: s.prior}
+}
+
+func (e *evaluation) isDone() bool {
+	return atomic.LoadInt32(&e.done) == 1
+}
+
+// evaluate evaluates state with evaluator, unless it has already been, and
+// returns the result.
+func (e *evaluation) evaluate(evaluator PriorEvaluator, state State) (map[Key]float64, float64) {
+	if !e.isDone() {
+		e.once.Do(func() {
+			e.priors, e.value = evaluator.Evaluate(state)
+			atomic.StoreInt32(&e.done, 1)
+		})
+	}
+	return e.priors, e.value
+}
+
+// copy returns an independent copy of a finished evaluation, or nil if it is
+// not finished.
+func (e *evaluation) copy() *evaluation {
+	if e == nil || !e.isDone() {
+		return nil
+	}
+	cpy := &evaluation{
+		done:   1,
+		priors: make(map[Key]float64, len(e.priors)),
+		value:  e.value,
+	}
+	for k, p := range e.priors {
+		cpy.priors[k] = p
+	}
+	return cpy
+}
@@ -0,0 +1,108 @@
+package montecarlo
+
+// GRAVEPolicy is an extension of the RAVEPolicy, based on generalized rapid
+// action value estimation (Cazenave 2015: Generalized Rapid Action Value
+// Estimation - IJCAI 2015). Rather than the all-moves-as-first statistics of
+// the node being selected from, the statistics of its closest ancestor (or
+// itself) where the same player moves, with at least Reference visits, are
+// used. Nodes deep in sparse trees are then guided by statistics gathered over
+// many more iterations.
+type GRAVEPolicy struct {
+	// Reference is the number of visits a node needs for its AMAF statistics
+	// to be used.
+	Reference int64
+	Beta      BetaSchedule
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
+}
+
+// NewGRAVEPolicy constructs a GRAVE policy with the passed reference number of
+// visits, using the minimum MSE schedule with the passed bias.
+func NewGRAVEPolicy(reference int64, bias float64) GRAVEPolicy {
+	return GRAVEPolicy{
+		Reference: reference,
+		Beta:      MinimumMSESchedule(bias),
+	}
+}
+
+// reference returns the closest ancestor of the passed node (or the node
+// itself) with enough visits for its AMAF statistics to be used. Only nodes
+// where the same player moves are considered, since AMAF statistics only hold
+// the actions of the player moving at each node. If there is no such node, the
+// furthest ancestor where the same player moves is returned.
+func (p GRAVEPolicy) reference(node *Node) *Node {
+	ref := node
+	// in a tree with transpositions, follow the path being selected through
+	for n := node; n != nil; n = n.table().parentOf(n) {
+		if n.Player() != node.Player() {
+			continue
+		}
+		ref = n
+		if n.Visits() >= p.Reference {
+			break
+		}
+	}
+	return ref
+}
+
+/******** IMPLEMENT Policy ********/
+
+// Select selects the child with the highest blended RAVE value, using the AMAF
+// statistics of the closest node on the way, where the same player moves, with
+// enough visits.
+func (p GRAVEPolicy) Select(node *Node, explorationParam float64) *Node {
+	beta := RAVEPolicy{Beta: p.Beta}.beta()
+	n := node
+	for n != nil && (n.IsRoot() || !n.IsTerminal()) {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if !n.IsExhausted() {
+			return p.Expand(n, explorationParam)
+		}
+		if n.IsLeaf() {
+			// a terminal root
+			break
+		}
+		parent, amafNode := n, p.reference(n)
+		_, n = parent.selectChildBy(func(child *Node) float64 {
+			return raveValue(parent, amafNode, child, beta, explorationParam)
+		})
+	}
+	return n
+}
+
+// Expand acts in exactly the same way as the UCTPolicy
+func (p GRAVEPolicy) Expand(node *Node, explorationParam float64) *Node {
+	return UCTPolicy{}.Expand(node, explorationParam)
+}
+
+// Simulate acts in exactly the same way as the RAVEPolicy
+func (p GRAVEPolicy) Simulate(node *Node) float64 {
+	return RAVEPolicy{Playout: p.Playout}.Simulate(node)
+}
+
+// Backpropagate acts in exactly the same way as the RAVEPolicy
+func (p GRAVEPolicy) Backpropagate(node *Node, score float64) {
+	RAVEPolicy{}.Backpropagate(node, score)
+}
+
+/******** IMPLEMENT AMAFPolicy ********/
+
+// SimulateAMAF acts in exactly the same way as the RAVEPolicy
+func (p GRAVEPolicy) SimulateAMAF(node *Node) (float64, []PlayedAction) {
+	return RAVEPolicy{Playout: p.Playout}.SimulateAMAF(node)
+}
+
+// BackpropagateAMAF acts in exactly the same way as the RAVEPolicy
+func (p GRAVEPolicy) BackpropagateAMAF(node *Node, score float64, played []PlayedAction) {
+	RAVEPolicy{}.BackpropagateAMAF(node, score, played)
+}
+
+func (p GRAVEPolicy) backpropagatePath(path path, result Result, visits int64) {
+	RAVEPolicy{}.backpropagatePath(path, result, visits)
+}
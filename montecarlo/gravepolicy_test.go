@@ -0,0 +1,147 @@
+package montecarlo
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// pickState is a single player game of picking "a" or "b" twice
+type pickState struct {
+	depth int
+}
+
+func (ps pickState) LegalActions() ActionSet {
+	actions := make(ActionSet)
+	if ps.depth < 2 {
+		for _, k := range []Key{"a", "b"} {
+			actions[k] = func(state State) State {
+				return pickState{state.(pickState).depth + 1}
+			}
+		}
+	}
+	return actions
+}
+
+func (ps pickState) Score(player uint) float64 {
+	return 0
+}
+
+func (ps pickState) Bias() float64 {
+	return 0
+}
+
+func (ps pickState) Copy() State {
+	return ps
+}
+
+func (ps pickState) Player() uint {
+	return 0
+}
+
+func (ps pickState) Policy() Policy {
+	return nil
+}
+
+// graveTestTree creates a well visited root whose AMAF statistics favour "a",
+// with a sparsely visited child whose own children are indistinguishable
+func graveTestTree() (*Node, *Node) {
+	root, _ := NewNode(1)
+	root.State = pickState{0}
+	root.visits = 100
+	root.amaf = &amafTable{actions: map[Key]*amafStats{
+		"a": {score: []float64{50}, visits: 50},
+		"b": {score: []float64{0}, visits: 50},
+	}}
+	sparse, _ := NewNode(1)
+	sparse.State = pickState{1}
+	sparse.visits = 2
+	root.SetChild("a", &sparse)
+	for _, k := range []Key{"a", "b"} {
+		leaf, _ := NewNode(1)
+		leaf.State = pickState{2}
+		leaf.visits = 1
+		sparse.SetChild(k, &leaf)
+	}
+	return &root, &sparse
+}
+
+func TestGRAVEReference(t *testing.T) {
+	root, sparse := graveTestTree()
+	assert.Equal(t, root, GRAVEPolicy{Reference: 50}.reference(sparse))
+	assert.Equal(t, sparse, GRAVEPolicy{Reference: 2}.reference(sparse))
+	// with no node visited enough, the furthest ancestor is used
+	assert.Equal(t, root, GRAVEPolicy{Reference: 1000}.reference(sparse))
+}
+
+func TestGRAVEUsesAncestorStatistics(t *testing.T) {
+	root, sparse := graveTestTree()
+	p := GRAVEPolicy{Reference: 50, Beta: HandSelectedSchedule(1000)}
+	for i := 0; i < 10; i++ {
+		selected := p.Select(sparse, 0)
+		assert.Equal(t, sparse.GetChild("a"), selected, "the root's AMAF statistics should favour a")
+	}
+	assert.Equal(t, int64(100), root.Visits())
+}
+
+// turnState is a two player game of picking "a" or "b" three times, with the
+// players taking turns
+type turnState struct {
+	depth int
+}
+
+func (ts turnState) LegalActions() ActionSet {
+	actions := make(ActionSet)
+	if ts.depth < 3 {
+		for _, k := range []Key{"a", "b"} {
+			actions[k] = func(state State) State {
+				return turnState{state.(turnState).depth + 1}
+			}
+		}
+	}
+	return actions
+}
+
+func (ts turnState) Score(player uint) float64 { return 0 }
+func (ts turnState) Bias() float64             { return 0 }
+func (ts turnState) Copy() State               { return ts }
+func (ts turnState) Player() uint              { return uint(ts.depth % 2) }
+func (ts turnState) Policy() Policy            { return nil }
+
+func TestGRAVEReferenceSamePlayer(t *testing.T) {
+	// the root's AMAF statistics favour player 0 taking "a", whereas those of
+	// the well visited opponent node below it, holding player 1's actions,
+	// would favour "b"
+	root, _ := NewNode(2)
+	root.State = turnState{0}
+	root.visits = 100
+	root.amaf = &amafTable{actions: map[Key]*amafStats{
+		"a": {score: []float64{50, 0}, visits: 50},
+		"b": {score: []float64{0, 50}, visits: 50},
+	}}
+	opponent, _ := NewNode(2)
+	opponent.State = turnState{1}
+	opponent.visits = 60
+	opponent.amaf = &amafTable{actions: map[Key]*amafStats{
+		"a": {score: []float64{0, 30}, visits: 30},
+		"b": {score: []float64{30, 0}, visits: 30},
+	}}
+	root.SetChild("a", &opponent)
+	sparse, _ := NewNode(2)
+	sparse.State = turnState{2}
+	sparse.visits = 2
+	opponent.SetChild("a", &sparse)
+	for _, k := range []Key{"a", "b"} {
+		leaf, _ := NewNode(2)
+		leaf.State = turnState{3}
+		leaf.visits = 1
+		sparse.SetChild(k, &leaf)
+	}
+
+	p := GRAVEPolicy{Reference: 50, Beta: HandSelectedSchedule(1000)}
+	assert.Equal(t, &root, p.reference(&sparse))
+	assert.Equal(t, &opponent, p.reference(&opponent))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, sparse.GetChild("a"), p.Select(&sparse, 0), "the root's AMAF statistics should favour a")
+	}
+}
@@ -0,0 +1,159 @@
+package montecarlo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Determinizer fills in the hidden information of a state, for Information Set
+// MCTS (see InformationSetSearch).
+type Determinizer interface {
+	// Determinize returns a concrete state, consistent with everything the
+	// observing player knows about the passed state, with any information
+	// hidden from the observer (such as the cards held by other players)
+	// sampled using the passed random source. The passed state must not be
+	// modified.
+	Determinize(state State, observer uint, r *rand.Rand) State
+}
+
+// Availability returns the number of times this node was available for
+// selection from its parent, see InformationSetSearch.
+func (node Node) Availability() int64 {
+	if node.avail == nil {
+		return 0
+	}
+	return node.avail.count
+}
+
+// availability is the number of times a node was available for selection from
+// its parent.
+type availability struct {
+	count int64
+}
+
+// copy returns an independent copy of the availability.
+func (a *availability) copy() *availability {
+	if a == nil {
+		return nil
+	}
+	cpy := *a
+	return &cpy
+}
+
+// merge adds the availability of other to this one, and returns the result,
+// which is a copy of other if this one is nil.
+func (a *availability) merge(other *availability) *availability {
+	if a == nil {
+		return other.copy()
+	}
+	if other != nil {
+		a.count += other.count
+	}
+	return a
+}
+
+// availabilityUCB gives the upper confidence bound of this node for the passed
+// player, with the number of times it was available in place of the number of
+// visits of its parent.
+func (node Node) availabilityUCB(explorationParam float64, player uint) float64 {
+	visits := float64(node.Visits())
+	if visits <= 0 {
+		return math.Inf(1)
+	}
+	expl := math.Max(explorationParam, 0)
+	ucb := node.Score(player)/visits + expl*math.Sqrt(float64(2)*math.Log(float64(node.Availability()))/visits)
+	if node.State != nil {
+		ucb += node.State.Bias()
+	}
+	return ucb
+}
+
+// InformationSetSearch searches via single-observer Information Set MCTS
+// (Cowling et al. 2012: Information Set Monte Carlo Tree Search - IEEE
+// transactions on computational intelligence and AI in games, vol. 4, no. 2),
+// for the best action to take from the root, for games with hidden
+// information. The observer is the player to move at the root.
+//
+// Every iteration descends the tree with a new determinization of the root
+// state. Nodes are identified by the keys of the actions leading to them, so a
+// node is shared by every determinization in which its actions are legal. Only
+// children whose actions are legal in the current determinization are
+// considered for selection, and the UCB of a child counts how many times it
+// was available for selection rather than the visits of its parent.
+//
+// The states of nodes are those of the first determinization they were created
+// with, so should only be relied upon for information known to every player,
+// such as whose turn it is.
+func (mcts MultiplayerMCTS) InformationSetSearch(determinizer Determinizer, level int64, expl float64) (SearchResult, error) {
+	start := time.Now()
+	root := mcts.tree.Root()
+	startVisits := root.Visits()
+	observer := root.Player()
+	for i := int64(0); i < level; i++ {
+		state := determinizer.Determinize(root.State, observer, root.Rand())
+		node, state := selectInformationSet(root, state, expl)
+		// simulate from the determinized state rather than the node's own
+		path := parentPath(node)
+		leaf := path.detach(newChildRand(node.Rand()))
+		leaf.State = state
+		backpropagate(path, simulate(leaf))
+	}
+	return mcts.result(expl, startVisits, start), nil
+}
+
+// selectInformationSet descends the tree from root with the passed
+// determinization, expanding a node if any of its legal actions have not been
+// tried. Returns the selected node along with the determinized state reached
+// there.
+func selectInformationSet(root *Node, state State, expl float64) (*Node, State) {
+	n := root
+	for {
+		legalActions := state.LegalActions()
+		if len(legalActions) <= 0 {
+			return n, state
+		}
+		untried := make(ActionSet)
+		for k, action := range legalActions {
+			if n.GetChild(k) == nil {
+				untried[k] = action
+			}
+		}
+		if k, action := randomAction(n.Rand(), untried); action != nil {
+			child, err := NewNode(n.NumPlayers())
+			if err != nil {
+				panic(fmt.Sprintf("%v", err))
+			}
+			state = (*action)(state.Copy())
+			child.State = state.Copy()
+			child.policy = child.State.Policy()
+			created := n.SetChild(k, &child)
+			n.addAvailability(legalActions)
+			return created, state
+		}
+		n.addAvailability(legalActions)
+		player := state.Player()
+		k, child := n.selectChildBy(func(child *Node) float64 {
+			if _, ok := legalActions[child.Key()]; !ok {
+				return math.Inf(-1)
+			}
+			return child.availabilityUCB(expl, player)
+		})
+		state = legalActions[k](state.Copy())
+		n = child
+	}
+}
+
+// addAvailability counts another selection at which the children of this node
+// for the passed actions were available.
+func (node *Node) addAvailability(actions ActionSet) {
+	for k := range actions {
+		if child := node.GetChild(k); child != nil {
+			if child.avail == nil {
+				child.avail = &availability{}
+			}
+			child.avail.count++
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// handState is a single player game with a hidden hand of two cards, always
+// holding "y" along with either "x" or "z". The player first guesses whether
+// "x" or "z" is in the hand, scoring 1 if they are right, then plays a card.
+type handState struct {
+	hand   [2]Key
+	guess  Key
+	played Key
+}
+
+func handActions() ActionSet {
+	actions := make(ActionSet)
+	for _, c := range []Key{"x", "z"} {
+		card := c
+		actions["guess_"+card.(string)] = func(state State) State {
+			s := state.(handState)
+			s.guess = card
+			return s
+		}
+	}
+	for _, c := range []Key{"x", "y", "z"} {
+		card := c
+		actions["play_"+card.(string)] = func(state State) State {
+			s := state.(handState)
+			s.played = card
+			return s
+		}
+	}
+	return actions
+}
+
+func (s handState) LegalActions() ActionSet {
+	all := handActions()
+	legal := make(ActionSet)
+	if s.guess == nil {
+		legal["guess_x"], legal["guess_z"] = all["guess_x"], all["guess_z"]
+	} else if s.played == nil {
+		for _, card := range s.hand {
+			k := "play_" + card.(string)
+			legal[k] = all[k]
+		}
+	}
+	return legal
+}
+
+func (s handState) Score(player uint) float64 {
+	if s.guess != nil && (s.hand[0] == s.guess || s.hand[1] == s.guess) {
+		return 1
+	}
+	return 0
+}
+
+func (s handState) Bias() float64  { return 0 }
+func (s handState) Copy() State    { return s }
+func (s handState) Player() uint   { return 0 }
+func (s handState) Policy() Policy { return UCTPolicy{} }
+
+// handDeterminizer deals "x" with a probability of pX, and "z" otherwise
+type handDeterminizer struct {
+	pX float64
+}
+
+func (d handDeterminizer) Determinize(state State, observer uint, r *rand.Rand) State {
+	s := state.(handState)
+	s.hand = [2]Key{"y", "z"}
+	if r.Float64() < d.pX {
+		s.hand = [2]Key{"x", "y"}
+	}
+	return s
+}
+
+func TestInformationSetSearch(t *testing.T) {
+	// the hand really holds "z", but that is hidden from the player
+	state := handState{hand: [2]Key{"y", "z"}}
+	mcts, err := NewMultiplayerMCTS(1, state, handActions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcts.SetRand(rand.New(rand.NewSource(1)))
+	result, err := mcts.InformationSetSearch(handDeterminizer{pX: 0.8}, 1000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Key("guess_x"), result.BestKey)
+	assert.Equal(t, int64(1000), result.Iterations)
+
+	// nodes are shared between determinizations, and only count the
+	// selections at which they were legal
+	guess := mcts.Tree().Root().GetChild("guess_x")
+	x, y, z := guess.GetChild("play_x"), guess.GetChild("play_y"), guess.GetChild("play_z")
+	if assert.NotNil(t, x) && assert.NotNil(t, y) && assert.NotNil(t, z) {
+		for _, c := range []*Node{x, y, z} {
+			assert.True(t, c.Availability() >= c.Visits())
+		}
+		assert.True(t, y.Availability() < guess.Visits())
+		assert.True(t, x.Availability()+z.Availability() < guess.Visits())
+		assert.True(t, y.Availability() > x.Availability())
+		assert.True(t, x.Availability() > z.Availability())
+	}
+}
+
+func TestPerfectInformationSearchCheats(t *testing.T) {
+	// searching the real state sees through the hidden hand
+	state := handState{hand: [2]Key{"y", "z"}}
+	mcts, err := NewMultiplayerMCTS(1, state, handActions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcts.SetRand(rand.New(rand.NewSource(1)))
+	result, err := mcts.Search(1000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Key("guess_z"), result.BestKey)
+}
+
+func TestInformationSetWireNodes(t *testing.T) {
+	// determinizations always deal the real hand, so that every node can be
+	// rebuilt from the real state
+	state := handState{hand: [2]Key{"y", "z"}}
+	mcts, err := NewMultiplayerMCTS(1, state, handActions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcts.SetRand(rand.New(rand.NewSource(1)))
+	if _, err := mcts.InformationSetSearch(handDeterminizer{pX: 0}, 200, 1); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := NewTree(1, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := toWireNodes(mcts.Tree().Root())
+	if err := nodes[0].build(nodes, tree.Root(), make(map[int]*Node)); err != nil {
+		t.Fatal(err)
+	}
+	// availability is sent along with the rest of each node's statistics
+	searched := []*Node{mcts.Tree().Root()}
+	built := []*Node{tree.Root()}
+	for len(searched) > 0 {
+		s, b := searched[0], built[0]
+		searched, built = searched[1:], built[1:]
+		assert.Equal(t, s.Visits(), b.Visits())
+		assert.Equal(t, s.Availability(), b.Availability())
+		for _, k := range sortedChildKeys(s.children) {
+			if assert.NotNil(t, b.GetChild(k)) {
+				searched = append(searched, s.children[k])
+				built = append(built, b.GetChild(k))
+			}
+		}
+	}
+}
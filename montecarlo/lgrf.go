@@ -0,0 +1,104 @@
+package montecarlo
+
+import "math/rand"
+
+// LGRF is the last-good-reply policy with forgetting (Baier & Drake 2010: The
+// Power of Forgetting: Improving the Last-Good-Reply Policy in Monte Carlo Go -
+// IEEE transactions on computational intelligence and AI in games, vol. 2, no.
+// 4), a playout policy for two-player games. For each action of a player, it
+// stores the reply of the other player in the last iteration that the replying
+// player won, in the tree or in the playout, and plays that reply first
+// whenever it is legal. Replies are forgotten once they appear in an iteration
+// the replying player lost. A player wins by having the strictly highest final
+// score; draws neither store nor forget replies.
+//
+// Other actions are chosen by the Fallback policy, or uniformly at random if it
+// is nil. Adaptive fallbacks, such as MAST, keep learning alongside LGRF.
+//
+// The replies belong to the searched tree, as the statistics of MAST do: they
+// are kept across searches of the same tree, including after it is advanced.
+type LGRF struct {
+	Fallback PlayoutPolicy
+}
+
+// NewLGRF constructs a last-good-reply policy, choosing any action without a
+// good reply by the passed fallback.
+func NewLGRF(fallback PlayoutPolicy) LGRF {
+	return LGRF{
+		Fallback: fallback,
+	}
+}
+
+// fallback returns the fallback policy of this policy, UniformPlayout by
+// default.
+func (pp LGRF) fallback() PlayoutPolicy {
+	if pp.Fallback == nil {
+		return UniformPlayout{}
+	}
+	return pp.Fallback
+}
+
+// Choose implements PlayoutPolicy, choosing by the fallback since there are no
+// replies outside of a search.
+func (pp LGRF) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	return pp.fallback().Choose(state, legalActions, r)
+}
+
+func (pp LGRF) chooseWith(stats *playoutStats, history []PlayedAction, state State, legalActions ActionSet, r *rand.Rand) Key {
+	if len(history) > 0 {
+		if last := history[len(history)-1]; last.Player != state.Player() {
+			if reply, ok := stats.reply(last); ok {
+				if _, legal := legalActions[reply]; legal {
+					return reply
+				}
+			}
+		}
+	}
+	if adaptive, ok := pp.fallback().(adaptivePlayout); ok {
+		return adaptive.chooseWith(stats, history, state, legalActions, r)
+	}
+	return pp.fallback().Choose(state, legalActions, r)
+}
+
+// Cutoff implements PlayoutCutoff, passing it on to the fallback.
+func (pp LGRF) Cutoff(state State, depth int) ([]float64, bool) {
+	return playoutCutoff(pp.Fallback, state, depth)
+}
+
+func (pp LGRF) learn(stats *playoutStats, actions []PlayedAction, final State, numPlayers uint) {
+	if winner, ok := terminalWinner(final, numPlayers); ok {
+		stats.updateReplies(actions, winner)
+	}
+	if adaptive, ok := pp.fallback().(adaptivePlayout); ok {
+		adaptive.learn(stats, actions, final, numPlayers)
+	}
+}
+
+// reply returns the last good reply to the passed action, if there is one.
+func (s *playoutStats) reply(action PlayedAction) (Key, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	reply, ok := s.replies[action]
+	return reply, ok
+}
+
+// updateReplies stores every reply of the winner to another player's action
+// in the passed actions, and forgets every reply of any other player.
+func (s *playoutStats) updateReplies(actions []PlayedAction, winner uint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.replies == nil {
+		s.replies = make(map[PlayedAction]Key)
+	}
+	for i := 1; i < len(actions); i++ {
+		previous, reply := actions[i-1], actions[i]
+		if previous.Player == reply.Player {
+			continue
+		}
+		if reply.Player == winner {
+			s.replies[previous] = reply.Key
+		} else if stored, ok := s.replies[previous]; ok && stored == reply.Key {
+			delete(s.replies, previous)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestLGRFStoresAndForgetsReplies(t *testing.T) {
+	game := guessGame(nil)
+	stats := &playoutStats{}
+	pick := PlayedAction{0, "a"}
+	played := []PlayedAction{pick, {1, "guess_a"}}
+
+	// player 1 guessed right, so their reply is stored
+	LGRF{}.learn(stats, played, game.state("a", "guess_a"), 2)
+	reply, ok := stats.reply(pick)
+	assert.True(t, ok)
+	assert.Equal(t, Key("guess_a"), reply)
+
+	// which is played whenever it is legal
+	r := rand.New(rand.NewSource(1))
+	state := game.state("a")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, Key("guess_a"), LGRF{}.chooseWith(stats, []PlayedAction{pick}, state, state.LegalActions(), r))
+	}
+
+	// and forgotten once it loses
+	LGRF{}.learn(stats, played, game.state("b", "guess_a"), 2)
+	_, ok = stats.reply(pick)
+	assert.False(t, ok)
+}
+
+func TestLGRFKeptAcrossSearches(t *testing.T) {
+	game := guessGame(UCTPolicy{Playout: NewLGRF(NewMAST(0.1))})
+	mcts, err := NewMultiplayerMCTS(2, game.state(), game.actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcts.SetRand(rand.New(rand.NewSource(1)))
+	if _, err := mcts.Search(100, 1); err != nil {
+		t.Fatal(err)
+	}
+	stats := mcts.Tree().Root().playoutStats()
+	assert.True(t, len(stats.replies) > 0)
+	// the fallback keeps learning alongside
+	assert.Equal(t, int64(2*100), actionVisits(stats))
+
+	if err := mcts.Advance("a"); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, stats == mcts.Tree().Root().playoutStats())
+	if _, err := mcts.Search(100, 1); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2*100+100), actionVisits(stats))
+}
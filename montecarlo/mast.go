@@ -0,0 +1,325 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// gramStats are the statistics of an n-gram of consecutive actions: the
+// total score of the player who took the last of them, over every iteration
+// in which they were taken. next holds the statistics of the n-grams made by
+// adding an earlier action to the front of this one.
+type gramStats struct {
+	visits int64
+	score  float64
+	next   map[PlayedAction]*gramStats
+}
+
+// child returns the statistics of the n-gram made by adding the passed action
+// to the front of this one, creating them if needed.
+func (g *gramStats) child(action PlayedAction) *gramStats {
+	if g.next == nil {
+		g.next = make(map[PlayedAction]*gramStats)
+	}
+	child, ok := g.next[action]
+	if !ok {
+		child = &gramStats{}
+		g.next[action] = child
+	}
+	return child
+}
+
+// merge adds the statistics of other and its longer n-grams to these.
+func (g *gramStats) merge(other *gramStats) {
+	g.visits += other.visits
+	g.score += other.score
+	for action, next := range other.next {
+		g.child(action).merge(next)
+	}
+}
+
+// playoutStats are the statistics of actions and n-grams of actions, along
+// with the last good reply to each action, learned from every iteration of a
+// search by adaptive playout policies (see MAST, NST and LGRF). They belong to
+// the searched tree, so independent searches never share them, and are safe
+// for concurrent use.
+type playoutStats struct {
+	lock    sync.RWMutex
+	grams   gramStats
+	replies map[PlayedAction]Key
+}
+
+// playoutStats returns the playout statistics of the tree this node belongs to,
+// nil if there are none.
+func (node *Node) playoutStats() *playoutStats {
+	for n := node; n != nil; n = n.parent {
+		if n.learning != nil && n.learning.stats != nil {
+			return n.learning.stats
+		}
+	}
+	return nil
+}
+
+// learning is what adaptive playout policies keep at the root of a tree and at
+// detached nodes: the statistics of the tree, shared by the detached nodes,
+// which also keep the path of actions to the node they were detached from.
+type learning struct {
+	stats *playoutStats
+	path  []PlayedAction
+}
+
+// copy returns an independent copy of the statistics and path.
+func (l *learning) copy() *learning {
+	if l == nil {
+		return nil
+	}
+	return &learning{
+		stats: l.stats.copy(),
+		path:  append([]PlayedAction(nil), l.path...),
+	}
+}
+
+// merge adds the statistics of other to these, and returns the result. Nodes
+// which don't keep statistics of their own are left without.
+func (l *learning) merge(other *learning) *learning {
+	if l != nil && other != nil {
+		l.stats.merge(other.stats)
+	}
+	return l
+}
+
+// update adds the final score of each acting player to the statistics of every
+// n-gram, of up to length actions, ending in one of the passed actions.
+func (s *playoutStats) update(actions []PlayedAction, final State, length int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, action := range actions {
+		score := final.Score(action.Player)
+		g := s.grams.child(action)
+		for j := 0; ; j++ {
+			g.visits++
+			g.score += score
+			if j+1 >= length || i-j-1 < 0 {
+				break
+			}
+			g = g.child(actions[i-j-1])
+		}
+	}
+}
+
+// values returns the value of the passed player taking each of the actions
+// with the passed keys, after the passed history of actions: the mean score of
+// the n-grams, of up to length actions, ending in the action. Longer n-grams
+// are only counted once they have minVisits visits. Actions that have never
+// been taken are given the default value.
+func (s *playoutStats) values(history []PlayedAction, player uint, keys []Key, length int, minVisits int64, def float64) []float64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		g := s.grams.next[PlayedAction{player, k}]
+		if g == nil || g.visits <= 0 {
+			values[i] = def
+			continue
+		}
+		sum, count := g.score/float64(g.visits), 1
+		for j := 1; j < length && len(history)-j >= 0; j++ {
+			g = g.next[history[len(history)-j]]
+			if g == nil {
+				break
+			}
+			if g.visits >= minVisits && g.visits > 0 {
+				sum += g.score / float64(g.visits)
+				count++
+			}
+		}
+		values[i] = sum / float64(count)
+	}
+	return values
+}
+
+// merge adds the statistics of other to these, the replies of other replace
+// any to the same actions.
+func (s *playoutStats) merge(other *playoutStats) {
+	if s == nil || other == nil || s == other {
+		return
+	}
+	other.lock.RLock()
+	defer other.lock.RUnlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.grams.merge(&other.grams)
+	for action, reply := range other.replies {
+		if s.replies == nil {
+			s.replies = make(map[PlayedAction]Key)
+		}
+		s.replies[action] = reply
+	}
+}
+
+// copy returns an independent copy of these statistics.
+func (s *playoutStats) copy() *playoutStats {
+	if s == nil {
+		return nil
+	}
+	cpy := &playoutStats{}
+	cpy.merge(s)
+	return cpy
+}
+
+// pathActions returns the actions leading from the root of this node's tree
+// to this node. Detached nodes keep the path of the node they were detached
+// from.
+func (node *Node) pathActions() []PlayedAction {
+	reversed := make([]PlayedAction, 0)
+	n := node
+	for ; n.parent != nil; n = n.parent {
+		reversed = append(reversed, PlayedAction{n.parent.Player(), n.key})
+	}
+	var prefix []PlayedAction
+	if n.learning != nil {
+		prefix = n.learning.path
+	}
+	path := append(make([]PlayedAction, 0, len(prefix)+len(reversed)), prefix...)
+	for i := len(reversed) - 1; i >= 0; i-- {
+		path = append(path, reversed[i])
+	}
+	return path
+}
+
+// adaptivePlayout is a PlayoutPolicy which learns from every iteration of a
+// search, see MAST and NST.
+type adaptivePlayout interface {
+	// chooseWith acts as Choose, given the statistics of the search and every
+	// action taken so far in the iteration.
+	chooseWith(stats *playoutStats, history []PlayedAction, state State, legalActions ActionSet, r *rand.Rand) Key
+	// learn updates the statistics of the search once an iteration, in which
+	// the passed actions were taken, has reached the passed final state.
+	learn(stats *playoutStats, actions []PlayedAction, final State, numPlayers uint)
+}
+
+// MAST is the move-average sampling technique (Finnsson & Björnsson 2008:
+// Simulation-Based Approach to General Game Playing - AAAI 2008): a playout
+// policy which learns the average final score of every action, by its key and
+// the player who took it, from every iteration of the search, in the tree and
+// in playouts. Actions are chosen by Gibbs sampling at Temperature (see
+// SoftmaxPlayout) over their average scores, or epsilon-greedily if Epsilon is
+// positive (see EpsilonGreedyPlayout). Actions that have never been taken are
+// given the Default score.
+//
+// The statistics belong to the searched tree: they are kept across searches of
+// the same tree, shared by tree-parallel and leaf-parallel workers, and merged
+// from the trees of root-parallel workers. Remote workers choose uniformly at
+// random, as does Choose on its own.
+type MAST struct {
+	Temperature float64
+	Epsilon     float64
+	Default     float64
+}
+
+// NewMAST constructs a MAST playout policy, Gibbs sampling at the passed
+// temperature.
+func NewMAST(temperature float64) MAST {
+	return MAST{
+		Temperature: temperature,
+	}
+}
+
+// NewEpsilonGreedyMAST constructs a MAST playout policy choosing the action
+// with the best average score, except with probability epsilon.
+func NewEpsilonGreedyMAST(epsilon float64) MAST {
+	return MAST{
+		Epsilon: epsilon,
+	}
+}
+
+// nst returns the NST policy of 1-grams equivalent to this policy.
+func (pp MAST) nst() NST {
+	return NST{
+		N:           1,
+		Temperature: pp.Temperature,
+		Epsilon:     pp.Epsilon,
+		Default:     pp.Default,
+	}
+}
+
+// Choose implements PlayoutPolicy, choosing uniformly at random since there are
+// no statistics outside of a search.
+func (pp MAST) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	return UniformPlayout{}.Choose(state, legalActions, r)
+}
+
+func (pp MAST) chooseWith(stats *playoutStats, history []PlayedAction, state State, legalActions ActionSet, r *rand.Rand) Key {
+	return pp.nst().chooseWith(stats, history, state, legalActions, r)
+}
+
+func (pp MAST) learn(stats *playoutStats, actions []PlayedAction, final State, numPlayers uint) {
+	pp.nst().learn(stats, actions, final, numPlayers)
+}
+
+// NST is the n-gram selection technique (Tak et al. 2012: N-Grams and the
+// Last-Good-Reply Policy Applied in General Game Playing - IEEE transactions
+// on computational intelligence and AI in games, vol. 4, no. 2), an extension
+// of MAST to n-grams of consecutive actions. The value of an action is the mean
+// of the average scores of the n-grams, of up to N actions, made by the action
+// and those taken before it. Longer n-grams are only counted once they have
+// been taken at least MinVisits times. Actions are chosen from their values as
+// with MAST, and the statistics are kept in the same way.
+type NST struct {
+	N           int
+	MinVisits   int64
+	Temperature float64
+	Epsilon     float64
+	Default     float64
+}
+
+// NewNST constructs an NST playout policy of n-grams of up to n actions, each
+// counted once taken minVisits times, Gibbs sampling at the passed temperature.
+func NewNST(n int, minVisits int64, temperature float64) NST {
+	return NST{
+		N:           n,
+		MinVisits:   minVisits,
+		Temperature: temperature,
+	}
+}
+
+// NewEpsilonGreedyNST constructs an NST playout policy of n-grams of up to n
+// actions, each counted once taken minVisits times, choosing the action with
+// the best value except with probability epsilon.
+func NewEpsilonGreedyNST(n int, minVisits int64, epsilon float64) NST {
+	return NST{
+		N:         n,
+		MinVisits: minVisits,
+		Epsilon:   epsilon,
+	}
+}
+
+// Choose implements PlayoutPolicy, choosing uniformly at random since there are
+// no statistics outside of a search.
+func (pp NST) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	return UniformPlayout{}.Choose(state, legalActions, r)
+}
+
+func (pp NST) chooseWith(stats *playoutStats, history []PlayedAction, state State, legalActions ActionSet, r *rand.Rand) Key {
+	keys := sortedActionKeys(legalActions)
+	values := stats.values(history, state.Player(), keys, pp.gramLength(), pp.MinVisits, pp.Default)
+	if pp.Epsilon > 0 || pp.Temperature <= 0 {
+		if r.Float64() < pp.Epsilon {
+			return keys[r.Intn(len(keys))]
+		}
+		return keys[greedyIndex(r, values)]
+	}
+	return keys[sampleIndex(r, gibbs(values, pp.Temperature))]
+}
+
+func (pp NST) learn(stats *playoutStats, actions []PlayedAction, final State, numPlayers uint) {
+	stats.update(actions, final, pp.gramLength())
+}
+
+// gramLength returns the length of the longest n-grams that are learned.
+func (pp NST) gramLength() int {
+	if pp.N < 1 {
+		return 1
+	}
+	return pp.N
+}
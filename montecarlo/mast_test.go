@@ -0,0 +1,73 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// actionVisits returns the total visits of every 1-gram
+func actionVisits(stats *playoutStats) int64 {
+	visits := int64(0)
+	for _, g := range stats.grams.next {
+		visits += g.visits
+	}
+	return visits
+}
+
+func TestPlayoutStatsNGrams(t *testing.T) {
+	stats := &playoutStats{}
+	pick := func(k Key) PlayedAction { return PlayedAction{0, k} }
+	guess := func(k Key) PlayedAction { return PlayedAction{1, k} }
+	game := guessGame(nil)
+	stats.update([]PlayedAction{pick("a"), guess("guess_a")}, game.state("a", "guess_a"), 2)
+	stats.update([]PlayedAction{pick("b"), guess("guess_a")}, game.state("b", "guess_a"), 2)
+
+	// guessing right scores 1 for player 1, and 0 otherwise
+	keys := []Key{"guess_a", "guess_b"}
+	assert.Equal(t, []float64{0.5, -1}, stats.values(nil, 1, keys, 1, 1, -1))
+	// the 2-grams after each pick are averaged with the 1-gram
+	assert.Equal(t, []float64{0.75, -1}, stats.values([]PlayedAction{pick("a")}, 1, keys, 2, 1, -1))
+	assert.Equal(t, []float64{0.25, -1}, stats.values([]PlayedAction{pick("b")}, 1, keys, 2, 1, -1))
+	// until they are visited enough
+	assert.Equal(t, []float64{0.5, -1}, stats.values([]PlayedAction{pick("b")}, 1, keys, 2, 2, -1))
+}
+
+func TestMASTLearnsFromSearch(t *testing.T) {
+	for _, playout := range []PlayoutPolicy{NewMAST(0.1), NewEpsilonGreedyMAST(0.2), NewNST(2, 1, 0.1)} {
+		game := pairGame(UCTPolicy{Playout: playout})
+		mcts, err := NewMultiplayerMCTS(1, game.state(), game.actions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mcts.SetRand(rand.New(rand.NewSource(1)))
+		if _, err := mcts.Search(200, 1); err != nil {
+			t.Fatal(err)
+		}
+		stats := mcts.Tree().Root().playoutStats()
+		// every iteration takes two actions, in the tree or in the playout
+		assert.Equal(t, int64(2*200), actionVisits(stats), "%T", playout)
+		values := stats.values(nil, 0, []Key{"a", "b"}, 1, 1, 0)
+		assert.True(t, values[0] > values[1], "%T", playout)
+	}
+}
+
+func TestMASTParallelSearch(t *testing.T) {
+	game := pairGame(UCTPolicy{Playout: NewNST(2, 1, 0.1)})
+	for _, search := range []func(MultiplayerMCTS) (SearchResult, error){
+		func(mcts MultiplayerMCTS) (SearchResult, error) { return mcts.TreeParallelSearch(4, 50, 1, 1) },
+		func(mcts MultiplayerMCTS) (SearchResult, error) { return mcts.RootParallelSearch(4, 50, 1) },
+	} {
+		mcts, err := NewMultiplayerMCTS(1, game.state(), game.actions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := search(mcts); err != nil {
+			t.Fatal(err)
+		}
+		// tree-parallel workers share the statistics, and root-parallel
+		// workers' statistics are merged
+		assert.Equal(t, int64(2*4*50), actionVisits(mcts.Tree().Root().playoutStats()))
+	}
+}
@@ -0,0 +1,148 @@
+package montecarlo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// MoveEquivalence gives the key of an action, taken by the player to move in
+// the passed state, as observed by the observing player. Actions which the
+// observer cannot tell apart (such as which card was played face down) should
+// be given the same key.
+type MoveEquivalence func(state State, key Key, observer uint) Key
+
+// MultiObserverMCTS searches via Multiple-Observer Information Set MCTS
+// (Cowling et al. 2012: Information Set Monte Carlo Tree Search - IEEE
+// transactions on computational intelligence and AI in games, vol. 4, no. 2),
+// for games with hidden information where players cannot observe every action.
+//
+// One tree is kept for each player, each only distinguishing the actions its
+// player can observe (see MoveEquivalence). Every iteration descends all of
+// the trees in lockstep with a new determinization of the root state (see
+// Determinizer): the player to move selects in their own tree, as in
+// InformationSetSearch, while every other tree follows the action as its
+// player observes it. A player always observes their own actions.
+type MultiObserverMCTS struct {
+	trees        []*Tree
+	determinizer Determinizer
+	equivalence  MoveEquivalence
+}
+
+// NewMultiObserverMCTS creates a new context from which to run MO-ISMCTS, with
+// one tree for each player.
+func NewMultiObserverMCTS(numPlayers uint, init State, actions map[Key]Action, determinizer Determinizer, equivalence MoveEquivalence) (MultiObserverMCTS, error) {
+	mcts := MultiObserverMCTS{
+		trees:        make([]*Tree, numPlayers),
+		determinizer: determinizer,
+		equivalence:  equivalence,
+	}
+	if numPlayers <= 0 {
+		return mcts, ZeroPlayerCount{numPlayers: numPlayers}
+	}
+	for p := range mcts.trees {
+		t, err := NewTree(numPlayers, init.Copy(), actions)
+		if err != nil {
+			return mcts, err
+		}
+		mcts.trees[p] = &t
+	}
+	return mcts, nil
+}
+
+// Tree returns the search tree of the passed player.
+func (mcts MultiObserverMCTS) Tree(player uint) *Tree {
+	return mcts.trees[player]
+}
+
+// SetRand sets the random source used by searches, see Tree.SetRand.
+func (mcts MultiObserverMCTS) SetRand(r *rand.Rand) {
+	for _, t := range mcts.trees {
+		t.SetRand(r)
+	}
+}
+
+// Search runs level iterations of MO-ISMCTS, for the best action for the
+// player to move at the root to take. The result describes that player's tree.
+func (mcts MultiObserverMCTS) Search(level int64, expl float64) (SearchResult, error) {
+	start := time.Now()
+	observer := mcts.trees[0].Root().Player()
+	searcher := MultiplayerMCTS{tree: mcts.trees[observer]}
+	startVisits := searcher.tree.Root().Visits()
+	for i := int64(0); i < level; i++ {
+		mcts.iterate(observer, expl)
+	}
+	return searcher.result(expl, startVisits, start), nil
+}
+
+// iterate runs a single iteration of MO-ISMCTS over every tree, with a new
+// determinization from the point of view of the observer.
+func (mcts MultiObserverMCTS) iterate(observer uint, expl float64) {
+	root := mcts.trees[observer].Root()
+	state := mcts.determinizer.Determinize(root.State, observer, root.Rand())
+	nodes := make([]*Node, len(mcts.trees))
+	for p, t := range mcts.trees {
+		nodes[p] = t.Root()
+	}
+	for expanded := false; !expanded; {
+		legalActions := state.LegalActions()
+		if len(legalActions) <= 0 {
+			break
+		}
+		mover := state.Player()
+		n := nodes[mover]
+		untried := make(ActionSet)
+		for k, action := range legalActions {
+			if n.GetChild(k) == nil {
+				untried[k] = action
+			}
+		}
+		var key Key
+		if k, action := randomAction(n.Rand(), untried); action != nil {
+			key, expanded = k, true
+		} else {
+			n.addAvailability(legalActions)
+			key, _ = n.selectChildBy(func(child *Node) float64 {
+				if _, ok := legalActions[child.Key()]; !ok {
+					return math.Inf(-1)
+				}
+				return child.availabilityUCB(expl, mover)
+			})
+		}
+		next := legalActions[key](state.Copy())
+		for p, node := range nodes {
+			observed := key
+			if uint(p) != mover {
+				observed = mcts.equivalence(state, key, uint(p))
+			}
+			nodes[p] = node.observedChild(observed, next)
+		}
+		if expanded {
+			n.addAvailability(legalActions)
+		}
+		state = next
+	}
+	// a single simulation is shared by every tree
+	leaf := parentPath(nodes[observer]).detach(newChildRand(nodes[observer].Rand()))
+	leaf.State = state
+	result := simulate(leaf)
+	for _, node := range nodes {
+		backpropagate(parentPath(node), result)
+	}
+}
+
+// observedChild returns the child of this node for the observed key, creating
+// it with the passed state if it does not exist yet.
+func (node *Node) observedChild(key Key, state State) *Node {
+	if child := node.GetChild(key); child != nil {
+		return child
+	}
+	child, err := NewNode(node.NumPlayers())
+	if err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+	child.State = state.Copy()
+	child.policy = child.State.Policy()
+	return node.SetChild(key, &child)
+}
@@ -0,0 +1,66 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// publicDeterminizer returns states as they are, nothing is hidden from the
+// player to move at the root
+type publicDeterminizer struct{}
+
+func (publicDeterminizer) Determinize(state State, observer uint, r *rand.Rand) State {
+	return state
+}
+
+// hiddenPick hides player 0's pick from player 1
+func hiddenPick(state State, key Key, observer uint) Key {
+	if key == "a" || key == "b" {
+		return "pick"
+	}
+	return key
+}
+
+func TestMultiObserverSearch(t *testing.T) {
+	game := guessGame(nil)
+	mcts, err := NewMultiObserverMCTS(2, game.state(), game.actions(), publicDeterminizer{}, hiddenPick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcts.SetRand(rand.New(rand.NewSource(1)))
+	result, err := mcts.Search(500, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(500), result.Iterations)
+	assert.Equal(t, 2, len(result.Children))
+
+	// player 0 sees their own pick
+	own := mcts.Tree(0).Root()
+	assert.NotNil(t, own.GetChild("a"))
+	assert.NotNil(t, own.GetChild("b"))
+	assert.Nil(t, own.GetChild("pick"))
+
+	// player 1 cannot tell the picks apart, so both share a node, below which
+	// only player 1's own guesses are distinguished
+	other := mcts.Tree(1).Root()
+	assert.Equal(t, 1, len(other.children))
+	pick := other.GetChild("pick")
+	if assert.NotNil(t, pick) {
+		assert.Equal(t, int64(500), pick.Visits())
+		assert.Equal(t, 2, len(pick.children))
+		for _, k := range []Key{"guess_a", "guess_b"} {
+			if guess := pick.GetChild(k); assert.NotNil(t, guess) {
+				assert.True(t, guess.Availability() >= guess.Visits())
+			}
+		}
+	}
+}
+
+func TestMultiObserverZeroPlayers(t *testing.T) {
+	game := guessGame(nil)
+	_, err := NewMultiObserverMCTS(0, game.state(), game.actions(), publicDeterminizer{}, hiddenPick)
+	assert.NotNil(t, err)
+}
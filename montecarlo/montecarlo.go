@@ -1,6 +1,10 @@
 package montecarlo
 
-import "sync"
+import (
+	"context"
+	"math/rand"
+	"time"
+)
 
 // ActionSet is a map from string to action
 type ActionSet map[Key]Action
@@ -38,88 +42,137 @@ type State interface {
 // implementation, including: its search tree; and its policy for operating on
 // the tree.
 type MultiplayerMCTS struct {
-	tree   Tree
-	policy Policy
+	tree     *Tree
+	policy   Policy
+	progress *progressReporter
 }
 
 // NewMultiplayerMCTS creates a new context from which to run a basic MCTS.
 func NewMultiplayerMCTS(numPlayers uint, init State, actions map[Key]Action) (MultiplayerMCTS, error) {
 	t, err := NewTree(numPlayers, init.Copy(), actions)
 	mcts := MultiplayerMCTS{
-		tree: t,
+		tree:     &t,
+		progress: &progressReporter{},
 	}
 	return mcts, err
 }
 
 // Search via MCTS, in a single-threaded manner, for the best action to take.
-// Returns the index of the best action to take, as well as the action itself
-// (according to the list of possible actions).
-func (mcts MultiplayerMCTS) Search(level int64, expl float64) (Key, *Action, error) {
-	for i := int64(0); i < level; i++ {
-		root := mcts.tree.Root()
-		node := root.Policy().Select(&root, expl)
-		node.Policy().Backpropagate(node, node.Policy().Simulate(node))
-		//log.Infof("finished %vth simulation", i)
+// Returns the result of the search, including the key of the best action to
+// take, as well as the action itself (according to the list of possible
+// actions).
+func (mcts MultiplayerMCTS) Search(level int64, expl float64) (SearchResult, error) {
+	if level <= 0 {
+		return mcts.result(expl, mcts.tree.Root().Visits(), time.Now()), nil
 	}
-	/*
-		// debugging output
-		for k, c := range mcts.tree.Root().children {
-			fmt.Printf("{%v: %v/%v | current player: %v | UCB %v}\n",
-				k,
-				c.ScoreVector(),
-				c.Visits(),
-				c.Player(),
-				c.UpperConfidenceBound(expl, c.Player()),
-			)
-			for k2, c2 := range c.children {
-				fmt.Printf("\t{ %v: %v/%v | current player: %v | UCB %v}\n",
-					k2,
-					c2.ScoreVector(),
-					c2.Visits(),
-					c2.Player(),
-					c2.UpperConfidenceBound(expl, c2.Player()),
-				)
-			}
+	return mcts.SearchContext(context.Background(), Budget{Iterations: level}, expl)
+}
+
+// SearchContext searches via MCTS, in a single-threaded manner, for the best
+// action to take. The search stops when the budget is used up or the context is
+// done, whichever comes first, or when a progress callback asks it to stop
+// (see OnProgress). Returns the result of the search, including the key of the
+// best action found so far, as well as the action itself (according to the list
+// of possible actions). The search also stops early once the root is solved
+// (see SolverPolicy and ScoreBoundedPolicy).
+func (mcts MultiplayerMCTS) SearchContext(ctx context.Context, budget Budget, expl float64) (SearchResult, error) {
+	start := time.Now()
+	if deadline, ok := budget.deadline(start); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	root := mcts.tree.Root()
+	startVisits := root.Visits()
+	lastReport := start
+	for i := int64(0); !budget.exhausted(i, mcts.tree.NumNodes()) && ctx.Err() == nil && !root.solved(); {
+		iterate(root, expl)
+		i++
+		if mcts.progress.due(i, &lastReport) && mcts.progress.callback(mcts.snapshot(i, start)) {
+			break
 		}
-	*/
-	// maximise exploitation over exploration by setting the exploration parameter
-	// to 0
-	key, _ := mcts.tree.root.selectBestChild(0)
-	action := mcts.tree.PossibleActions()[key]
-	return key, &action, nil
+	}
+	return mcts.result(expl, startVisits, start), nil
+}
+
+// Advance moves the root of the search tree to the state reached by taking the
+// action with the passed key, which may be the action chosen by a search or the
+// move of an opponent. Statistics gathered below the new root are kept, so the
+// next search starts from where the last one left off.
+func (mcts MultiplayerMCTS) Advance(key Key) error {
+	return mcts.tree.Advance(key)
+}
+
+// SetRand sets the random source used by searches, see Tree.SetRand.
+func (mcts MultiplayerMCTS) SetRand(r *rand.Rand) {
+	mcts.tree.SetRand(r)
+}
+
+// Tree returns the search tree used by this MCTS.
+func (mcts MultiplayerMCTS) Tree() *Tree {
+	return mcts.tree
+}
+
+// ParallelSearch searches via MCTS for the best action to take, splitting the
+// search into jobs for the passed workers according to the passed strategy.
+// Returns the result of the search, including the statistics of each worker.
+func (mcts MultiplayerMCTS) ParallelSearch(strategy Strategy, workers []Worker, level int64, expl float64) (SearchResult, error) {
+	start := time.Now()
+	startVisits := mcts.tree.Root().Visits()
+	scheduler := NewScheduler(workers)
+	err := strategy.Search(mcts.tree, scheduler, level, expl)
+	stats := scheduler.Close()
+	result := mcts.result(expl, startVisits, start)
+	result.Workers = stats
+	return result, err
 }
 
 // RootParallelSearch searches via MCTS, in a root-parallel manner, for the best
-// action to take. Returns the key of the best action to take, as well as the
-// action itself (according to the list of possible actions).
-// TODO fix this
-func (mcts MultiplayerMCTS) RootParallelSearch(numThreads int, level int64, expl float64) (Key, *Action, error) {
-	// an output channel of the final trees each thread produces
-	trees := make(chan *Tree, numThreads)
-	var counter sync.WaitGroup
-	counter.Add(numThreads)
-	for threadno := 0; threadno < numThreads; threadno++ {
-		go func() {
-			defer counter.Done()
-			// create a separate copy of the initial tree for this thread
-			tree := mcts.tree.Copy()
-			for i := int64(0); i < level; i++ {
-				root := tree.Root()
-				node := root.Policy().Select(&root, expl)
-				node.Policy().Backpropagate(node, node.Policy().Simulate(node))
-			}
-			trees <- tree
-		}()
+// action to take, using numThreads local workers. See RootParallel.
+func (mcts MultiplayerMCTS) RootParallelSearch(numThreads int, level int64, expl float64) (SearchResult, error) {
+	return mcts.ParallelSearch(RootParallel{}, LocalWorkers(numThreads), level, expl)
+}
+
+// TreeParallelSearch searches via MCTS, in a tree-parallel manner, for the best
+// action to take, using numThreads local workers. See TreeParallel.
+func (mcts MultiplayerMCTS) TreeParallelSearch(numThreads int, level int64, expl float64, virtualLoss int64) (SearchResult, error) {
+	return mcts.ParallelSearch(TreeParallel{VirtualLoss: virtualLoss}, LocalWorkers(numThreads), level, expl)
+}
+
+// LeafParallelSearch searches via MCTS, in a leaf-parallel manner, for the best
+// action to take, using numThreads local workers. See LeafParallel.
+func (mcts MultiplayerMCTS) LeafParallelSearch(numThreads int, level int64, expl float64) (SearchResult, error) {
+	return mcts.ParallelSearch(LeafParallel{}, LocalWorkers(numThreads), level, expl)
+}
+
+// bestAction returns the key of the best action to take from the root of the
+// tree, as well as the action itself. Only exploitation is considered, other
+// than preferring actions proven to be best (see SolverPolicy and
+// ScoreBoundedPolicy). At simultaneous-move nodes the joint action of every
+// player's most chosen action is returned.
+func (mcts MultiplayerMCTS) bestAction() (Key, *Action) {
+	root := mcts.tree.Root()
+	var key Key
+	if ss, ok := root.simultaneous(); ok && root.joint.playerStats() != nil {
+		// each player's own most chosen action
+		key = root.bestJointAction(ss)
+	} else {
+		key, _ = root.selectFinalChild()
 	}
-	// merge all created trees
-	go func() {
-		for t := range trees {
-			mcts.tree.Merge(*t)
-		}
-	}()
-	// wait for all searches to finish
-	counter.Wait()
-	key, _ := mcts.tree.root.selectBestChild(0)
 	action := mcts.tree.PossibleActions()[key]
-	return key, &action, nil
+	return key, &action
+}
+
+// iterate runs a single iteration of MCTS from the passed root: a node is
+// selected (and possibly expanded), simulated from, and the result is
+// propagated back up the tree.
+func iterate(root *Node, expl float64) {
+	path := selectPath(root, expl)
+	leaf := path.node()
+	if root.table() != nil {
+		// a shared node is simulated from as if it was reached by the path
+		// that was taken, using the same random source
+		leaf = path.detach(leaf.Rand())
+	}
+	backpropagate(path, simulate(leaf))
 }
@@ -0,0 +1,252 @@
+package montecarlo_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestRootParallelSearch(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.RootParallelSearch(4, 200, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.NotNil(t, result.BestAction)
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal, "root-parallel search should pick a legal action")
+	assert.Equal(t, 4, len(result.Workers))
+	for i, s := range result.Workers {
+		assert.Equal(t, i, s.Worker)
+		assert.Equal(t, int64(200), s.Iterations)
+		assert.True(t, s.Nodes > 1)
+	}
+	// every iteration of every worker should be accounted for in the merged tree
+	root := ai.Tree().Root()
+	assert.Equal(t, int64(4*200), root.Visits())
+	childVisits := int64(0)
+	for k := range initState().LegalActions() {
+		if c := root.GetChild(k); c != nil {
+			childVisits += c.Visits()
+		}
+	}
+	assert.Equal(t, root.Visits(), childVisits)
+}
+
+func TestTreeParallelSearch(t *testing.T) {
+	makeActions()
+	for _, virtualLoss := range []int64{0, 3} {
+		ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		result, err := ai.TreeParallelSearch(4, 200, 1/math.Sqrt2, virtualLoss)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		assert.NotNil(t, result.BestAction)
+		_, legal := initState().LegalActions()[result.BestKey]
+		assert.True(t, legal, "tree-parallel search should pick a legal action")
+		assert.Equal(t, 4, len(result.Workers))
+		// all virtual loss should have been removed once the search finished
+		root := ai.Tree().Root()
+		assert.Equal(t, int64(4*200), root.Visits())
+		childVisits := int64(0)
+		for k := range initState().LegalActions() {
+			if c := root.GetChild(k); c != nil {
+				childVisits += c.Visits()
+			}
+		}
+		assert.Equal(t, root.Visits(), childVisits)
+	}
+}
+
+func TestLeafParallelSearch(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.LeafParallelSearch(4, 100, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.NotNil(t, result.BestAction)
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal, "leaf-parallel search should pick a legal action")
+	simulations := int64(0)
+	for _, s := range result.Workers {
+		simulations += s.Iterations
+	}
+	assert.Equal(t, int64(4*100), simulations)
+	// each selected node should have been visited once per simulation
+	assert.Equal(t, int64(4*100), ai.Tree().Root().Visits())
+}
+
+// countingWorker counts the jobs it has carried out
+type countingWorker struct {
+	montecarlo.LocalWorker
+	jobs *int
+}
+
+func (w countingWorker) Work(job montecarlo.Job) montecarlo.Result {
+	*w.jobs++
+	return w.LocalWorker.Work(job)
+}
+
+func TestParallelSearchWithCustomWorkers(t *testing.T) {
+	makeActions()
+	counts := make([]int, 3)
+	workers := make([]montecarlo.Worker, len(counts))
+	for i := range workers {
+		workers[i] = countingWorker{jobs: &counts[i]}
+	}
+	strategies := []montecarlo.Strategy{
+		montecarlo.RootParallel{},
+		montecarlo.LeafParallel{},
+		montecarlo.TreeParallel{VirtualLoss: 1},
+	}
+	for _, strategy := range strategies {
+		for i := range counts {
+			counts[i] = 0
+		}
+		ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		result, err := ai.ParallelSearch(strategy, workers, 50, 1/math.Sqrt2)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		assert.Equal(t, len(workers), len(result.Workers))
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		assert.True(t, total > 0, "workers should have been given jobs")
+	}
+}
+
+func TestSearchContextIterationBudget(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.SearchContext(context.Background(), montecarlo.Budget{Iterations: 300}, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal)
+	assert.Equal(t, int64(300), ai.Tree().Root().Visits())
+}
+
+func TestSearchContextNodeBudget(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	_, err = ai.SearchContext(context.Background(), montecarlo.Budget{Nodes: 50}, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	// the UCT policy adds at most one node per iteration
+	assert.Equal(t, 50, ai.Tree().NumNodes())
+}
+
+func TestSearchContextDuration(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	start := time.Now()
+	result, err := ai.SearchContext(context.Background(), montecarlo.Budget{Duration: 20 * time.Millisecond}, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.True(t, time.Since(start) < time.Second, "search should stop once its duration is used up")
+	assert.True(t, ai.Tree().Root().Visits() > 0)
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal)
+}
+
+func TestSearchContextCancelled(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	// a search with no budget only stops when cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	result, err := ai.SearchContext(ctx, montecarlo.Budget{}, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal, "a cancelled search should return the best action found so far")
+}
+
+func TestAdvanceKeepsSubtree(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.Search(500, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	child := ai.Tree().Root().GetChild(result.BestKey)
+	visits := child.Visits()
+	size := child.Size()
+	if err := ai.Advance(result.BestKey); err != nil {
+		assert.Fail(t, err.Error())
+	}
+	root := ai.Tree().Root()
+	assert.Equal(t, child, root)
+	assert.True(t, root.IsRoot(), "the new root should be detached from its parent")
+	assert.Equal(t, visits, root.Visits())
+	assert.Equal(t, size, ai.Tree().NumNodes())
+	assert.Equal(t, (*result.BestAction)(initState()), root.State)
+	// the next search should carry on from the kept statistics
+	_, err = ai.Search(100, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, visits+100, root.Visits())
+}
+
+func TestAdvanceUnexploredMove(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	// an opponent's move that the search has never seen
+	if err := ai.Advance("SET_X_1_1"); err != nil {
+		assert.Fail(t, err.Error())
+	}
+	root := ai.Tree().Root()
+	assert.True(t, root.IsRoot())
+	assert.Equal(t, int64(0), root.Visits())
+	assert.Equal(t, actions["SET_X_1_1"](initState()), root.State)
+	// moves that aren't legal can't be advanced to
+	err = ai.Advance("SET_X_1_1")
+	_, ok := err.(montecarlo.IllegalChildKey)
+	assert.True(t, ok, "expected IllegalChildKey error")
+}
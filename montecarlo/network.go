@@ -0,0 +1,463 @@
+package montecarlo
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// StateCodec converts states to and from bytes, so that they can be sent to
+// workers in other processes.
+type StateCodec interface {
+	Encode(state State) ([]byte, error)
+	Decode(data []byte) (State, error)
+}
+
+/*
+ The wire protocol between a NetworkWorker (the coordinator's side) and a
+ worker process started with ServeWorker. Each job is a single gob-encoded
+ wireRequest, answered by a single gob-encoded wireResponse. Action keys are
+ sent as gob interface values, so any key type other than the basic types must
+ be registered with gob.Register.
+*/
+
+type wireRequest struct {
+	Kind             JobKind
+	NumPlayers       uint
+	State            []byte
+	Iterations       int64
+	ExplorationParam float64
+	Seed             int64
+	Deadline         time.Time
+	Transpositions   bool
+}
+
+type wireResponse struct {
+	Score  float64
+	Played []PlayedAction
+	Scores []float64
+	Nodes  []wireNode
+	Err    string
+}
+
+// wireNode is the statistics of a node, without its state. The nodes of a
+// searched tree are sent as a list, starting with the root, in which each node
+// appears once however many paths reach it; children refer to their index in
+// the list.
+type wireNode struct {
+	Score    []float64
+	Visits   int64
+	Prior    float64
+	Proven   bool
+	Winner   uint
+	Pess     []float64
+	Opt      []float64
+	AMAF     []wireAMAF
+	Avail    int64
+	Joint    [][]ActionStats
+	Picks    []int
+	Children []wireChild
+}
+
+type wireAMAF struct {
+	Key    Key
+	Score  []float64
+	Visits int64
+}
+
+// wireChild is the edge to a child, with the visits of the edge itself in a
+// tree with transpositions.
+type wireChild struct {
+	Key    Key
+	Node   int
+	Visits int64
+}
+
+/*-------- COORDINATOR --------*/
+
+// NetworkWorker is a Worker that carries out jobs in a remote worker process
+// over TCP (see ServeWorker).
+//
+// Each job must be answered within Timeout of being sent, unless it is zero. A
+// job which isn't answered in time fails with WorkerTimeout, and the
+// connection is closed, since a late answer would otherwise be taken as the
+// answer to the next job.
+type NetworkWorker struct {
+	Timeout time.Duration
+
+	conn  net.Conn
+	enc   *gob.Encoder
+	dec   *gob.Decoder
+	codec StateCodec
+}
+
+// DialWorker connects to the worker process listening on the passed address.
+// The codec must be able to decode the states it encodes in the worker process.
+func DialWorker(address string, codec StateCodec) (*NetworkWorker, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &NetworkWorker{
+		conn:  conn,
+		enc:   gob.NewEncoder(conn),
+		dec:   gob.NewDecoder(conn),
+		codec: codec,
+	}, nil
+}
+
+// Close closes the connection to the worker process.
+func (w *NetworkWorker) Close() error {
+	return w.conn.Close()
+}
+
+// Work sends the passed job to the worker process, and waits for its result.
+//
+// The searched tree of a SubtreeJob comes back as statistics alone, since
+// sending the state of every node would cost far more than searching it, so
+// the tree can't be combined with the job's own tree by Node.Merge, which
+// needs the states of the nodes it creates. Instead the tree is rebuilt on the
+// job's own tree, by replaying the actions leading to each node from the
+// tree's root state. RootParallel then merges the rebuilt tree into the
+// searched one as it does the trees of local workers.
+func (w *NetworkWorker) Work(job Job) Result {
+	// clear the deadline of the previous job if this one has none
+	deadline, _ := Budget{Duration: w.Timeout}.deadline(time.Now())
+	if err := w.conn.SetDeadline(deadline); err != nil {
+		return Result{Err: err}
+	}
+	result := w.work(job, deadline)
+	if err, ok := result.Err.(net.Error); ok && err.Timeout() {
+		w.conn.Close()
+		result.Err = WorkerTimeout{deadline}
+	}
+	return result
+}
+
+// work carries out the passed job in the worker process, which must answer by
+// the passed deadline.
+func (w *NetworkWorker) work(job Job, deadline time.Time) Result {
+	req := wireRequest{
+		Kind:             job.Kind,
+		Iterations:       job.Iterations,
+		ExplorationParam: job.ExplorationParam,
+		Deadline:         deadline,
+	}
+	var node *Node
+	switch job.Kind {
+	case SimulationJob:
+		node = job.Node
+	case SubtreeJob:
+		node = job.Tree.Root()
+		req.Transpositions = node.table() != nil
+	default:
+		return Result{Err: UnknownJobKind(job.Kind)}
+	}
+	req.NumPlayers = node.NumPlayers()
+	// the worker process's random source is seeded from the job's, so that
+	// network searches are as reproducible as local ones
+	req.Seed = node.Rand().Int63()
+	data, err := w.codec.Encode(node.State)
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.State = data
+	if err := w.enc.Encode(req); err != nil {
+		return Result{Err: err}
+	}
+	var resp wireResponse
+	if err := w.dec.Decode(&resp); err != nil {
+		return Result{Err: err}
+	}
+	if resp.Err != "" {
+		return Result{Err: RemoteWorkerError(resp.Err)}
+	}
+	if job.Kind == SimulationJob {
+		return Result{Score: resp.Score, Played: resp.Played, Scores: resp.Scores}
+	}
+	if len(resp.Nodes) > 0 {
+		if err := resp.Nodes[0].build(resp.Nodes, node, make(map[int]*Node)); err != nil {
+			return Result{Err: err}
+		}
+	}
+	return Result{Tree: job.Tree}
+}
+
+// build adds the statistics of this wire node to the passed node, creating
+// children by applying the legal action of each child's key. The statistics of
+// the other nodes sent are looked up in nodes, and those already added to a
+// node are kept in built, so that a child shared by several paths has them
+// added once.
+func (wn wireNode) build(nodes []wireNode, node *Node, built map[int]*Node) error {
+	// the visits of edges are sent separately
+	node.addStats(wn.stats(node.NumPlayers()))
+	for _, c := range wn.Children {
+		if c.Node <= 0 || c.Node >= len(nodes) {
+			return RemoteWorkerError(fmt.Sprintf("no node sent for child %v", c.Key))
+		}
+		child := node.GetChild(c.Key)
+		if child == nil {
+			action, ok := node.State.LegalActions()[c.Key]
+			if !ok {
+				return IllegalChildKey{c.Key}
+			}
+			n, err := NewNode(node.NumPlayers())
+			if err != nil {
+				return err
+			}
+			n.State = action(node.State.Copy())
+			n.policy = n.State.Policy()
+			// in a tree with transpositions, this links the child if it has
+			// already been built through another path
+			child = node.SetChild(c.Key, &n)
+		}
+		node.addEdgeVisits(child, c.Visits)
+		if built[c.Node] == child {
+			continue
+		}
+		built[c.Node] = child
+		if err := nodes[c.Node].build(nodes, child, built); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*-------- WORKER PROCESS --------*/
+
+// ListenAndServeWorker listens on the passed TCP address and serves jobs from
+// coordinators, see ServeWorker.
+func ListenAndServeWorker(address string, codec StateCodec) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return ServeWorker(l, codec)
+}
+
+// ServeWorker accepts connections from coordinators (see DialWorker) on the
+// passed listener, and carries out the jobs they send until the listener is
+// closed. Each connection is served on its own goroutine, jobs are carried out
+// by a LocalWorker.
+func ServeWorker(l net.Listener, codec StateCodec) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, codec)
+	}
+}
+
+// serveConn carries out jobs sent on the passed connection until it is closed.
+// The answer to each job must be sent by the job's deadline, after which the
+// coordinator has given up on it and closed the connection.
+func serveConn(conn net.Conn, codec StateCodec) {
+	defer conn.Close()
+	enc := gob.NewEncoder(conn)
+	dec := gob.NewDecoder(conn)
+	for {
+		// wait for as long as it takes the coordinator to send the next job
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return
+		}
+		var req wireRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		resp := serveRequest(req, codec)
+		if err := conn.SetDeadline(req.Deadline); err != nil {
+			return
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// serveRequest carries out a single job.
+func serveRequest(req wireRequest, codec StateCodec) wireResponse {
+	state, err := codec.Decode(req.State)
+	if err != nil {
+		return wireResponse{Err: err.Error()}
+	}
+	tree, err := NewTree(req.NumPlayers, state, nil)
+	if err != nil {
+		return wireResponse{Err: err.Error()}
+	}
+	tree.SetRand(rand.New(rand.NewSource(req.Seed)))
+	if req.Transpositions {
+		tree.EnableTranspositions()
+	}
+	root := tree.Root()
+	if policy := state.Policy(); policy != nil {
+		root.policy = policy
+	}
+	job := Job{
+		Kind:             req.Kind,
+		Node:             root,
+		Tree:             &tree,
+		Iterations:       req.Iterations,
+		ExplorationParam: req.ExplorationParam,
+	}
+	result := LocalWorker{}.Work(job)
+	if result.Err != nil {
+		return wireResponse{Err: result.Err.Error()}
+	}
+	if req.Kind == SimulationJob {
+		return wireResponse{Score: result.Score, Played: result.Played, Scores: result.Scores}
+	}
+	return wireResponse{Nodes: toWireNodes(root)}
+}
+
+// toWireNodes converts the passed node, and every node below it, to their
+// wire form. Each node is converted once, however many paths reach it.
+func toWireNodes(root *Node) []wireNode {
+	index := map[*Node]int{root: 0}
+	order := []*Node{root}
+	wire := make([]wireNode, 0)
+	for i := 0; i < len(order); i++ {
+		node := order[i]
+		wn := wireNode{Score: node.ScoreVector(), Visits: node.Visits()}
+		node.amaf.toWire(&wn)
+		node.puct.toWire(&wn)
+		node.proof.toWire(&wn)
+		node.bounds.toWire(&wn)
+		node.avail.toWire(&wn)
+		node.joint.toWire(&wn)
+		for _, k := range sortedChildKeys(node.children) {
+			child := node.children[k]
+			if child == nil {
+				continue
+			}
+			j, ok := index[child]
+			if !ok {
+				j = len(order)
+				index[child] = j
+				order = append(order, child)
+			}
+			wn.Children = append(wn.Children, wireChild{k, j, node.edgeVisits(child)})
+		}
+		wire = append(wire, wn)
+	}
+	return wire
+}
+
+// stats returns a node with the statistics of this wire node, but without a
+// state or children.
+func (wn wireNode) stats(numPlayers uint) Node {
+	// will not throw any error since we're already using a valid player count
+	n, _ := NewNode(numPlayers)
+	copy(n.score, wn.Score)
+	n.visits = wn.Visits
+	n.amaf = wn.amafTable()
+	n.puct = wn.puctStats()
+	n.proof = wn.solverProof()
+	n.bounds = wn.scoreBounds()
+	n.avail = wn.availability()
+	n.joint = wn.simultaneousStats()
+	return n
+}
+
+// toWire writes the table to the passed wire node.
+func (t *amafTable) toWire(wn *wireNode) {
+	if t == nil {
+		return
+	}
+	for k, stats := range t.actions {
+		wn.AMAF = append(wn.AMAF, wireAMAF{k, stats.score, stats.visits})
+	}
+}
+
+// amafTable returns the all-moves-as-first statistics sent, nil if none were.
+func (wn wireNode) amafTable() *amafTable {
+	if len(wn.AMAF) == 0 {
+		return nil
+	}
+	t := &amafTable{actions: make(map[Key]*amafStats, len(wn.AMAF))}
+	for _, a := range wn.AMAF {
+		t.actions[a.Key] = &amafStats{score: a.Score, visits: a.Visits}
+	}
+	return t
+}
+
+// toWire writes the prior to the passed wire node. The evaluation is not sent,
+// it is made again by the coordinator if needed.
+func (s *puctStats) toWire(wn *wireNode) {
+	if s != nil {
+		wn.Prior = s.prior
+	}
+}
+
+// puctStats returns the prior sent, nil if none was.
+func (wn wireNode) puctStats() *puctStats {
+	if wn.Prior == 0 {
+		return nil
+	}
+	return &puctStats{prior: wn.Prior}
+}
+
+// toWire writes the proof to the passed wire node.
+func (p *solverProof) toWire(wn *wireNode) {
+	if p != nil {
+		wn.Proven, wn.Winner = true, p.winner
+	}
+}
+
+// solverProof returns the proof sent, nil if the node was not proven.
+func (wn wireNode) solverProof() *solverProof {
+	if !wn.Proven {
+		return nil
+	}
+	return &solverProof{winner: wn.Winner}
+}
+
+// toWire writes the bounds to the passed wire node.
+func (b *scoreBounds) toWire(wn *wireNode) {
+	if b != nil {
+		wn.Pess, wn.Opt = b.pess, b.opt
+	}
+}
+
+// scoreBounds returns the bounds sent, nil if none were.
+func (wn wireNode) scoreBounds() *scoreBounds {
+	if wn.Pess == nil {
+		return nil
+	}
+	return &scoreBounds{pess: wn.Pess, opt: wn.Opt}
+}
+
+// toWire writes the availability to the passed wire node.
+func (a *availability) toWire(wn *wireNode) {
+	if a != nil {
+		wn.Avail = a.count
+	}
+}
+
+// availability returns the availability sent, nil if the node was never
+// available.
+func (wn wireNode) availability() *availability {
+	if wn.Avail == 0 {
+		return nil
+	}
+	return &availability{count: wn.Avail}
+}
+
+// toWire writes the statistics to the passed wire node.
+func (s *simultaneousStats) toWire(wn *wireNode) {
+	if s != nil {
+		wn.Joint, wn.Picks = s.players, s.picks
+	}
+}
+
+// simultaneousStats returns the joint action statistics sent, nil if none
+// were.
+func (wn wireNode) simultaneousStats() *simultaneousStats {
+	if wn.Joint == nil && wn.Picks == nil {
+		return nil
+	}
+	return &simultaneousStats{players: wn.Joint, picks: wn.Picks}
+}
@@ -0,0 +1,301 @@
+package montecarlo_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// gameStateCodec encodes a tictactoe state as one byte per cell, followed by a
+// byte for whose turn it is
+type gameStateCodec struct{}
+
+func (c gameStateCodec) Encode(state montecarlo.State) ([]byte, error) {
+	s, ok := state.(gameState)
+	if !ok {
+		return nil, errors.New("not a tictactoe state")
+	}
+	data := make([]byte, 0, 10)
+	for _, row := range s.board {
+		for _, c := range row {
+			data = append(data, byte(c))
+		}
+	}
+	if s.turn {
+		return append(data, 1), nil
+	}
+	return append(data, 0), nil
+}
+
+func (c gameStateCodec) Decode(data []byte) (montecarlo.State, error) {
+	if len(data) != 10 {
+		return nil, errors.New("tictactoe state should be 10 bytes")
+	}
+	s := initState()
+	for i := 0; i < 9; i++ {
+		s.board[i/3][i%3] = cell(data[i])
+	}
+	s.turn = data[9] == 1
+	return s, nil
+}
+
+// startNetworkWorkers serves n worker processes on loopback, and connects to
+// each of them.
+func startNetworkWorkers(t *testing.T, n int) ([]montecarlo.Worker, func()) {
+	var listeners []net.Listener
+	var workers []montecarlo.Worker
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go montecarlo.ServeWorker(l, gameStateCodec{})
+		listeners = append(listeners, l)
+		w, err := montecarlo.DialWorker(l.Addr().String(), gameStateCodec{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		workers = append(workers, w)
+	}
+	return workers, func() {
+		for i := range workers {
+			workers[i].(*montecarlo.NetworkWorker).Close()
+			listeners[i].Close()
+		}
+	}
+}
+
+// TestMain serves a worker instead of running the tests when the test binary
+// is started as a worker process by startWorkerProcesses.
+func TestMain(m *testing.M) {
+	if os.Getenv("FULLMONTE_WORKER") != "" {
+		makeActions()
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(l.Addr())
+		montecarlo.ServeWorker(l, gameStateCodec{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startWorkerProcesses starts n copies of the test binary as worker processes,
+// and connects to each of them.
+func startWorkerProcesses(t *testing.T, n int) ([]montecarlo.Worker, func()) {
+	var cmds []*exec.Cmd
+	var workers []montecarlo.Worker
+	stop := func() {
+		for _, w := range workers {
+			w.(*montecarlo.NetworkWorker).Close()
+		}
+		for _, cmd := range cmds {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+	}
+	for i := 0; i < n; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		cmd.Env = append(os.Environ(), "FULLMONTE_WORKER=1")
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+		// the worker prints the address it listens on once it's ready
+		address, err := bufio.NewReader(out).ReadString('\n')
+		if err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		w, err := montecarlo.DialWorker(address[:len(address)-1], gameStateCodec{})
+		if err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		workers = append(workers, w)
+	}
+	return workers, stop
+}
+
+func TestNetworkRootParallelSearch(t *testing.T) {
+	makeActions()
+	workers, stop := startNetworkWorkers(t, 3)
+	defer stop()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.ParallelSearch(montecarlo.RootParallel{}, workers, 100, 1/math.Sqrt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal, "network search should pick a legal action")
+	for _, s := range result.Workers {
+		assert.Equal(t, int64(100), s.Iterations)
+		assert.True(t, s.Nodes > 1)
+	}
+	// the remote trees should have been rebuilt and merged
+	root := ai.Tree().Root()
+	assert.Equal(t, int64(3*100), root.Visits())
+	childVisits := int64(0)
+	for k := range initState().LegalActions() {
+		if c := root.GetChild(k); c != nil {
+			childVisits += c.Visits()
+			assert.NotNil(t, c.State)
+		}
+	}
+	assert.Equal(t, root.Visits(), childVisits)
+}
+
+func TestNetworkRootParallelTranspositions(t *testing.T) {
+	makeActions()
+	workers, stop := startNetworkWorkers(t, 2)
+	defer stop()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	ai.Tree().EnableTranspositions()
+	if _, err := ai.ParallelSearch(montecarlo.RootParallel{}, workers, 300, 1); err != nil {
+		t.Fatal(err)
+	}
+	// the nodes shared in the remote trees should be shared once rebuilt
+	root := ai.Tree().Root()
+	assert.Equal(t, int64(2*300), root.Visits())
+	assert.Equal(t, keyedNodes(t, root), ai.Tree().NumNodes())
+}
+
+func TestNetworkLeafParallelSearch(t *testing.T) {
+	makeActions()
+	workers, stop := startNetworkWorkers(t, 2)
+	defer stop()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	_, err = ai.ParallelSearch(montecarlo.LeafParallel{}, workers, 50, 1/math.Sqrt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2*50), ai.Tree().Root().Visits())
+}
+
+func TestNetworkWorkerProcesses(t *testing.T) {
+	makeActions()
+	workers, stop := startWorkerProcesses(t, 2)
+	defer stop()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.ParallelSearch(montecarlo.RootParallel{}, workers, 100, 1/math.Sqrt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range result.Workers {
+		assert.Equal(t, int64(100), s.Iterations)
+	}
+	_, err = ai.ParallelSearch(montecarlo.LeafParallel{}, workers, 50, 1/math.Sqrt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2*100+2*50), ai.Tree().Root().Visits())
+}
+
+func TestNetworkWorkerTimeoutPerJob(t *testing.T) {
+	makeActions()
+	workers, stop := startNetworkWorkers(t, 1)
+	defer stop()
+	w := workers[0].(*montecarlo.NetworkWorker)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	w.Timeout = 200 * time.Millisecond
+	if _, err := ai.ParallelSearch(montecarlo.RootParallel{}, workers, 10, 1/math.Sqrt2); err != nil {
+		t.Fatal(err)
+	}
+	// the deadline of the previous job has passed, but this job has none
+	time.Sleep(250 * time.Millisecond)
+	w.Timeout = 0
+	_, err = ai.ParallelSearch(montecarlo.RootParallel{}, workers, 10, 1/math.Sqrt2)
+	assert.Nil(t, err)
+}
+
+// failingCodec can't decode anything
+type failingCodec struct{ gameStateCodec }
+
+func (c failingCodec) Decode(data []byte) (montecarlo.State, error) {
+	return nil, errors.New("can't decode")
+}
+
+func TestNetworkWorkerRemoteError(t *testing.T) {
+	makeActions()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go montecarlo.ServeWorker(l, failingCodec{})
+	w, err := montecarlo.DialWorker(l.Addr().String(), gameStateCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	_, err = ai.ParallelSearch(montecarlo.RootParallel{}, []montecarlo.Worker{w}, 10, 1/math.Sqrt2)
+	_, ok := err.(montecarlo.RemoteWorkerError)
+	assert.True(t, ok, "expected RemoteWorkerError")
+}
+
+func TestNetworkWorkerTimeout(t *testing.T) {
+	makeActions()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// accept the connection, but never answer
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+	w, err := montecarlo.DialWorker(l.Addr().String(), gameStateCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Timeout = 50 * time.Millisecond
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	_, err = ai.ParallelSearch(montecarlo.RootParallel{}, []montecarlo.Worker{w}, 10, 1/math.Sqrt2)
+	_, ok := err.(montecarlo.WorkerTimeout)
+	assert.True(t, ok, "expected WorkerTimeout")
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"time"
+	"reflect"
 )
 
 // Key is the key type used to map to child nodes (actions)
@@ -23,6 +23,29 @@ type Node struct {
 	// from this node.
 	children map[Key]*Node
 	policy   Policy
+	// size is the number of nodes in the tree rooted at this node, including
+	// itself.
+	size int
+	// rand is the random source used by this node and its descendants, nil if
+	// the node should use its parent's.
+	rand *rand.Rand
+	// key is the key of the action leading to this node from its parent.
+	key Key
+	// The statistics kept by each algorithm, nil until they are first needed:
+	// all-moves-as-first statistics (see RAVEPolicy), the prior and evaluation
+	// of PUCTPolicy, the proof of SolverPolicy, the bounds of
+	// ScoreBoundedPolicy, availability (see InformationSetSearch), the joint
+	// action statistics of SimultaneousPolicy, the links of nodes shared
+	// between paths (see Tree.EnableTranspositions) and the statistics of
+	// adaptive playout policies (see MAST).
+	amaf     *amafTable
+	puct     *puctStats
+	proof    *solverProof
+	bounds   *scoreBounds
+	avail    *availability
+	joint    *simultaneousStats
+	shared   *sharing
+	learning *learning
 }
 
 // NewNode creates a fully formed MCTS tree node, containing a nil state however.
@@ -34,6 +57,7 @@ func NewNode(numPlayers uint) (Node, error) {
 		parent:     nil,
 		children:   make(map[Key]*Node, 0),
 		policy:     UCTPolicy{},
+		size:       1,
 	}
 	if numPlayers <= 0 {
 		return n, ZeroPlayerCount(n)
@@ -56,22 +80,90 @@ func (node Node) String() string {
 	return out
 }
 
-// Copy returns a deep copy of this node
+// Copy returns a deep copy of this node and all of its children. The copy has
+// no parent, so it is the root of its own tree. Nodes shared between several
+// paths are copied once, into a transposition table of the copy's own.
 func (node Node) Copy() *Node {
+	cpy := node.copyShared(make(map[*Node]*Node))
+	if node.table() != nil {
+		cpy.size = newTranspositions().register(cpy)
+	}
+	return cpy
+}
+
+// copyShared returns a deep copy of this node and all of its children, reusing
+// the passed copies of shared nodes, keyed by the original node.
+func (node Node) copyShared(copies map[*Node]*Node) *Node {
 	// will not throw any error since we're already using a valid player count
 	cpy, _ := NewNode(node.NumPlayers())
-	cpy.children = make(map[Key]*Node)
-	// add the nodes of this tree into the copy
-	// will not throw an error since the player counts are the same
-	_ = cpy.Merge(node)
+	copy(cpy.score, node.score)
+	cpy.visits = node.visits
+	cpy.policy = node.policy
+	cpy.key = node.key
+	cpy.amaf = node.amaf.copy()
+	cpy.puct = node.puct.copy()
+	cpy.proof = node.proof.copy()
+	cpy.bounds = node.bounds.copy()
+	cpy.avail = node.avail.copy()
+	cpy.joint = node.joint.copy()
+	cpy.learning = node.learning.copy()
+	if node.State != nil {
+		cpy.State = node.State.Copy()
+	}
+	for k, child := range node.children {
+		if child == nil {
+			continue
+		}
+		if shared, ok := copies[child]; ok {
+			cpy.children[k] = shared
+			continue
+		}
+		c := child.copyShared(copies)
+		if child.table() != nil {
+			copies[child] = c
+		}
+		cpy.SetChild(k, c)
+	}
+	cpy.shared = node.shared.copy(copies)
 	return &cpy
 }
 
 // Merge two nodes and all their children: add all nodes from other into this
 // node's tree of children. If both trees have the same node, then their Score
-// and Visit values are added.
+// and Visit values are added. In a tree with transpositions, nodes are the
+// same if their states have the same key (see Tree.EnableTranspositions),
+// otherwise if they are reached by the same path.
 func (node *Node) Merge(other Node) error {
 	//TODO make a version of Merge that does not create side-effects
+	if err := node.mergeStats(other); err != nil {
+		return err
+	}
+	if node.table() != nil {
+		return node.mergeTranspositions(&other)
+	}
+	// add children
+	for k, otherChild := range other.children {
+		if otherChild == nil {
+			continue
+		}
+		child, ok := node.children[k]
+		if !ok {
+			// if a child with that key does not exist on this node, take a copy
+			// of the other node's subtree
+			node.SetChild(k, otherChild.Copy())
+			continue
+		}
+		// recurse, merging children
+		if err := child.Merge(*otherChild); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeStats adds the statistics of other to those of this node, leaving
+// their children alone.
+func (node *Node) mergeStats(other Node) error {
 	players := node.NumPlayers()
 	// check for incompatibility
 	if other.NumPlayers() != players {
@@ -86,40 +178,52 @@ func (node *Node) Merge(other Node) error {
 			other.Player(),
 		}
 	}
-	if node.State != other.State {
+	if !node.sameState(other.State) {
 		return MergeStateMismatch{
 			node.State,
 			other.State,
 		}
 	}
-	// add the other node's values to this node
-	for i := uint(0); i < players; i++ {
-		node.score[i] += other.score[i]
-	}
-	node.visits += other.Visits()
-	if other.State != nil {
-		node.State = other.State.Copy()
-	}
-	// add children
-	for k, otherChild := range other.children {
-		if otherChild == nil {
-			continue
-		}
-		if _, ok := node.children[k]; !ok {
-			// if a child with that key does not exist on this node, make it
-			n, err := NewNode(players)
-			if err != nil {
-				// this really shouldn't happen
-				return err
-			}
-			node.SetChild(k, &n)
+	node.addStats(other)
+	return nil
+}
+
+// addStats adds the statistics of other to those of this node, without
+// checking that they are for the same state.
+func (node *Node) addStats(other Node) {
+	for i := range node.score {
+		if i < len(other.score) {
+			node.score[i] += other.score[i]
 		}
-		// recurse, merging children
-		if otherChild != nil {
-			node.GetChild(k).Merge(*otherChild)
+	}
+	node.visits += other.visits
+	// the edges to children are merged along with the children
+	node.amaf = node.amaf.merge(other.amaf)
+	node.puct = node.puct.merge(other.puct)
+	node.proof = node.proof.merge(other.proof)
+	node.bounds = node.bounds.merge(other.bounds)
+	node.avail = node.avail.merge(other.avail)
+	node.joint = node.joint.merge(other.joint)
+	node.learning = node.learning.merge(other.learning)
+}
+
+// sameState returns true if the passed state is the same as this node's: if
+// their keys are equal in a tree with transpositions, or if they are deeply
+// equal otherwise.
+func (node *Node) sameState(state State) bool {
+	if node.table() != nil {
+		if key, ok := stateKey(node.State); ok {
+			other, ok := stateKey(state)
+			return ok && key == other
 		}
 	}
-	return nil
+	return sameState(node.State, state)
+}
+
+// sameState compares two states deeply, many State implementations contain
+// slices or maps and so can't be compared with the == operator.
+func sameState(one, other State) bool {
+	return reflect.DeepEqual(one, other)
 }
 
 // UpperConfidenceBound (UCB) describes the upper end of the confidence bound
@@ -147,60 +251,111 @@ func (node Node) UpperConfidenceBound(explorationParameter float64, player uint)
 		// all nodes with no visits will have an equal chance (positive infinity)
 		return math.Inf(1)
 	}
-	nScore := node.Score(player)
+	// the mean score of chance nodes is weighted by the probability of each
+	// outcome
+	nScore := node.expectedScore(player)
 	pVisits := float64(node.Parent().Visits())
-	return (nScore / nVisits) + expl*math.Sqrt(float64(2)*math.Log(pVisits)/nVisits)
+	return nScore + expl*math.Sqrt(float64(2)*math.Log(pVisits)/nVisits)
 }
 
 // selectBestChild returns the string for an action with the highest rated
 // confidence, as well as the resulting state (Node). The string is a key to
 // the MCTS tree's list of possible actions.
 // If the node has no children, then the empty string is returned along with the
-// node itself.
-func (node Node) selectBestChild(explorationParam float64) (Key, *Node) {
-	maxUCB := math.Inf(-1)
-	maxIndex := interface{}(nil)
-	if node.IsLeaf() {
-		return "", &node
+// node itself. Ties are broken using the node's random source.
+func (node *Node) selectBestChild(explorationParam float64) (Key, *Node) {
+	return node.selectChildBy(func(child *Node) float64 {
+		return node.childValue(child, explorationParam)
+	})
+}
+
+// childValue gives the upper confidence bound of the passed child of this
+// node, including the bias of the child's state.
+func (node *Node) childValue(child *Node, explorationParam float64) float64 {
+	//we calculate the upper confidence bound for the child's player itself;
+	//not the root node's player - this is because we imagine that each
+	//player will try to maximise their own reward (Browne et al. page 10 -
+	//"Multiplayer MCTS").
+	var ucb float64
+	if node.table() != nil {
+		ucb = node.edgeConfidenceBound(child, explorationParam)
+	} else {
+		ucb = child.UpperConfidenceBound(explorationParam, node.Player())
 	}
-	epsilon := 0.000001
-	maxima := make(map[float64][]Key)
-	//find the highest upper-confidence-bound in this node's children
-	for i, n := range node.children {
-		//we calculate the upper confidence bound for the child's player itself;
-		//not the root node's player - this is because we imagine that each
-		//player will try to maximise their own reward (Browne et al. page 10 -
-		//"Multiplayer MCTS").
-		ucb := n.UpperConfidenceBound(explorationParam, node.Player())
-		// add selection bias for nodes containing states that specifiy it
-		bias := float64(0)
-		if n.State != nil {
-			bias = n.State.Bias()
-		}
-		if ucb+bias >= maxUCB {
-			//compare floats within range of epsilon
-			if (maxUCB - ucb) <= epsilon {
-				maxUCB = ucb
-				maxIndex = i
-				maxima[maxUCB] = make([]Key, 0)
-			}
-			maxima[maxUCB] = append(maxima[maxUCB], maxIndex)
-		}
+	// add selection bias for nodes containing states that specifiy it
+	if child.State != nil {
+		ucb += child.State.Bias()
 	}
+	return ucb
+}
+
+// selectChildBy returns the key and child with the highest value according to
+// the passed function, ties are broken using the node's random source. If the
+// node has no children, then the empty string is returned along with the node
+// itself.
+func (node *Node) selectChildBy(value func(child *Node) float64) (Key, *Node) {
+	if node.IsLeaf() {
+		return "", node
+	}
+	maxima := node.maxChildKeys(value)
 	//if there is no true maximum, pick a random one
-	if len(maxima[maxUCB]) > 1 {
-		n := len(maxima[maxUCB])
-		rand.Seed(time.Now().UTC().UnixNano())
-		target := rand.Intn(n)
-		i := 0
-		for k := range maxima[maxUCB] {
-			if i == target {
-				return maxima[maxUCB][k], node.children[maxima[maxUCB][k]]
-			}
-			i++
+	k := maxima[0]
+	if len(maxima) > 1 {
+		k = maxima[node.Rand().Intn(len(maxima))]
+	}
+	child := node.children[k]
+	node.table().reached(node, k, child)
+	return k, child
+}
+
+// firstChildBy acts like selectChildBy, but breaks ties by taking the first
+// key in sorted order, so that neither the node's random source nor the path
+// being selected through a transposition table are touched.
+func (node *Node) firstChildBy(value func(child *Node) float64) (Key, *Node) {
+	if node.IsLeaf() {
+		return "", node
+	}
+	k := node.maxChildKeys(value)[0]
+	return k, node.children[k]
+}
+
+// maxChildKeys returns the keys, in sorted order, of the children with the
+// highest value according to the passed function.
+func (node *Node) maxChildKeys(value func(child *Node) float64) []Key {
+	max := math.Inf(-1)
+	maxima := make([]Key, 0)
+	for _, i := range sortedChildKeys(node.children) {
+		v := value(node.children[i])
+		if closeEnough(v, max) {
+			maxima = append(maxima, i)
+		} else if v > max || len(maxima) == 0 {
+			max = v
+			maxima = append(maxima[:0], i)
 		}
 	}
-	return maxIndex, node.children[maxIndex]
+	return maxima
+}
+
+// closeEnough compares floats within a small range of each other, infinities
+// of the same sign are equal.
+func closeEnough(a, b float64) bool {
+	epsilon := 0.000001
+	return a == b || math.Abs(a-b) <= epsilon
+}
+
+// Key returns the key of the action leading to this node from its parent, nil
+// for nodes which have never had a parent.
+func (node Node) Key() Key {
+	return node.key
+}
+
+// Prior returns the prior probability of the action leading to this node being
+// chosen, as given by a PriorEvaluator when the node was created.
+func (node Node) Prior() float64 {
+	if node.puct == nil {
+		return 0
+	}
+	return node.puct.prior
 }
 
 // Parent returns the parent of this node.
@@ -209,22 +364,85 @@ func (node Node) Parent() *Node {
 }
 
 // SetChild sets the child of this node (at the specified index) to the passed
-// child.
-func (node *Node) SetChild(index Key, child *Node) {
+// child, and returns it. In a tree with transpositions, if a node with the same
+// state as the child already exists, then that node is linked and returned
+// instead.
+func (node *Node) SetChild(index Key, child *Node) *Node {
+	node.RemoveChild(index)
+	shared := node.table().lookup(node, child)
+	if shared != nil {
+		// shared nodes keep their parent, the path selection took to reach
+		// them is recorded instead
+		node.children[index] = shared
+		node.table().reached(node, index, shared)
+		return shared
+	}
 	child.parent = node
+	child.key = index
 	node.children[index] = child
+	node.addSize(child.size)
+	node.table().register(child)
+	node.table().reached(node, index, child)
+	return child
 }
 
 // RemoveChild removes the child with the specified index from this node's set
 // of children (if it exists).
 func (node *Node) RemoveChild(index Key) {
-	_, ok := node.children[index]
+	child, ok := node.children[index]
 	if ok {
-		node.children[index].parent = nil
 		delete(node.children, index)
+		if node.shared != nil {
+			delete(node.shared.edges, child)
+		}
+		if child != nil {
+			// shared children may have been selected through another parent
+			if child.parent == node {
+				child.parent = nil
+			}
+			node.addSize(-child.size)
+		}
 	}
 }
 
+// addSize adds to the size of this node and all of its ancestors.
+func (node *Node) addSize(size int) {
+	for n := node; n != nil; n = n.parent {
+		n.size += size
+	}
+}
+
+// height returns the length of the longest path from this node to a leaf.
+func (node *Node) height() int {
+	return node.heightMemo(make(map[*Node]int))
+}
+
+// heightMemo returns the height of this node, reusing the passed heights of
+// nodes already measured, so that nodes shared between paths are only
+// measured once.
+func (node *Node) heightMemo(heights map[*Node]int) int {
+	if h, ok := heights[node]; ok {
+		return h
+	}
+	height := 0
+	for _, child := range node.children {
+		if child == nil {
+			continue
+		}
+		if h := child.heightMemo(heights) + 1; h > height {
+			height = h
+		}
+	}
+	heights[node] = height
+	return height
+}
+
+// Size returns the number of nodes in the tree rooted at this node, including
+// the node itself.
+func (node Node) Size() int {
+	return node.size
+}
+
 // GetChild returns the child of the specified index from this node's set of
 // children.
 func (node Node) GetChild(index Key) *Node {
@@ -257,7 +475,23 @@ func (node Node) Visits() int64 {
 
 // AddVisit increments the number of visits of this node.
 func (node *Node) AddVisit() {
-	node.visits++
+	node.AddVisits(1)
+}
+
+// AddVisits increases the number of visits of this node by the passed amount,
+// along with those of the edge leading to it from its parent in a tree with
+// transpositions.
+func (node *Node) AddVisits(visits int64) {
+	node.addVisitsFrom(node.parent, visits)
+}
+
+// addVisitsFrom increases the number of visits of this node, along with those
+// of the edge leading to it from the passed parent, which may be nil.
+func (node *Node) addVisitsFrom(parent *Node, visits int64) {
+	node.visits += visits
+	if parent != nil {
+		parent.addEdgeVisits(node, visits)
+	}
 }
 
 // IsExhausted returns true if all possible actions have been created for this
@@ -303,6 +537,19 @@ func (node *Node) SetScore(player uint, score float64) {
 	node.score[player] = score
 }
 
+// Rand returns the random source used by this node: the source of its closest
+// ancestor (or itself) that has been given one (see Tree.SetRand), or a shared
+// source seeded from the time otherwise. Copies of a node do not keep its
+// random source.
+func (node *Node) Rand() *rand.Rand {
+	for n := node; n != nil; n = n.parent {
+		if n.rand != nil {
+			return n.rand
+		}
+	}
+	return defaultRand
+}
+
 // Policy returns the policy used by this node
 func (node Node) Policy() Policy {
 	return node.policy
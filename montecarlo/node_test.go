@@ -401,3 +401,75 @@ func TestNodeWithStateIsExhausted(t *testing.T) {
 	nodeWithGrandchildren.State = simpleStateImplementation{}
 	assert.True(t, nodeWithGrandchildren.IsExhausted())
 }
+
+func TestNodeCopyIsIndependent(t *testing.T) {
+	nodeTestSetup()
+	nodeWithGrandchildren.State = simpleStateImplementation{1}
+	cpy := nodeWithGrandchildren.Copy()
+	assert.True(t, cpy.IsRoot())
+	assert.Equal(t, nodeWithGrandchildren.State, cpy.State)
+	assert.Equal(t, nodeWithGrandchildren.Visits(), cpy.Visits())
+	for k, c := range cpy.children {
+		assert.Equal(t, cpy, c.Parent(), "copied children should point to the copied parent")
+		assert.Equal(t, nodeWithGrandchildren.GetChild(k).ScoreVector(), c.ScoreVector())
+	}
+	// modifying the copy should leave the original untouched
+	cpy.GetChild("0").AddVisit()
+	cpy.GetChild("0").SetScore(0, 100)
+	assert.Equal(t, int64(10), nodeWithGrandchildren.GetChild("0").Visits())
+	assert.Equal(t, float64(0), nodeWithGrandchildren.GetChild("0").Score(0))
+}
+
+func TestNodeMergeAddsMissingChildren(t *testing.T) {
+	nodeTestSetup()
+	err := normal.Merge(nodeWithGrandchildren)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, nodeWithGrandchildren.Visits(), normal.Visits())
+	assert.Equal(t, len(nodeWithGrandchildren.children), len(normal.children))
+	for k, c := range normal.children {
+		assert.Equal(t, &normal, c.Parent())
+		assert.Equal(t, nodeWithGrandchildren.GetChild(k).Visits(), c.Visits())
+		assert.Equal(t, len(nodeWithGrandchildren.GetChild(k).children), len(c.children))
+	}
+}
+
+func TestNodeSize(t *testing.T) {
+	nodeTestSetup()
+	assert.Equal(t, 1, normal.Size())
+	assert.Equal(t, 11, nodeWithChildren.Size())
+	assert.Equal(t, 111, nodeWithGrandchildren.Size())
+	// sizes of ancestors should be kept up to date
+	child := nodeWithGrandchildren.GetChild("0")
+	grandchild, err := NewNode(1)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	child.GetChild("0").SetChild("0", &grandchild)
+	assert.Equal(t, 12, child.Size())
+	assert.Equal(t, 112, nodeWithGrandchildren.Size())
+	nodeWithGrandchildren.RemoveChild("0")
+	assert.Equal(t, 100, nodeWithGrandchildren.Size())
+	// replacing a child should not count it twice
+	nodeWithGrandchildren.SetChild("1", nodeWithGrandchildren.GetChild("1").Copy())
+	assert.Equal(t, 100, nodeWithGrandchildren.Size())
+}
+
+func TestHeightSharedNodes(t *testing.T) {
+	// a ladder in which both nodes of each rung are children of both nodes of
+	// the rung above, so there are 2^depth paths to the bottom
+	depth := 60
+	root, _ := NewNode(2)
+	rung := []*Node{&root}
+	for i := 0; i < depth; i++ {
+		left, _ := NewNode(2)
+		right, _ := NewNode(2)
+		for _, n := range rung {
+			n.children["left"] = &left
+			n.children["right"] = &right
+		}
+		rung = []*Node{&left, &right}
+	}
+	assert.Equal(t, depth, root.height())
+}
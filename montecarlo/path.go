@@ -0,0 +1,94 @@
+package montecarlo
+
+import "math/rand"
+
+// edge links a node to a parent, from which it is reached by the action with
+// key.
+type edge struct {
+	parent *Node
+	node   *Node
+	key    Key
+}
+
+// path is the path taken by an iteration of a search: the edge leading to each
+// node on it, from the selected node up to the root, whose parent is nil.
+// Nodes shared between paths (see Tree.EnableTranspositions) only have the
+// parent they were created below, so results are propagated along the path
+// rather than along the parents of the selected node.
+type path []edge
+
+// parentPath returns the path from the passed node up to the root of its tree
+// through the parent of each node.
+func parentPath(node *Node) path {
+	p := make(path, 0)
+	for n := node; n != nil; n = n.parent {
+		p = append(p, edge{n.parent, n, n.key})
+	}
+	return p
+}
+
+// selectPath selects a node to simulate from using the root's policy, and
+// returns the path leading to it. In a tree with transpositions, this is the
+// path selection went through, otherwise it is made of the node's ancestors.
+func selectPath(root *Node, expl float64) path {
+	t := root.table()
+	if t != nil {
+		t.selected = make(map[*Node]edge)
+		defer func() { t.selected = nil }()
+	}
+	p := make(path, 0)
+	for n := root.Policy().Select(root, expl); n != nil; {
+		e := t.edgeTo(n)
+		if n == root {
+			e.parent = nil
+		}
+		p = append(p, e)
+		n = e.parent
+	}
+	return p
+}
+
+// node returns the selected node, at the end of the path.
+func (p path) node() *Node {
+	return p[0].node
+}
+
+// actions returns the actions taken along the path, from the root to the
+// selected node.
+func (p path) actions() []PlayedAction {
+	actions := make([]PlayedAction, 0, len(p))
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].parent != nil {
+			actions = append(actions, PlayedAction{p[i].parent.Player(), p[i].key})
+		}
+	}
+	return actions
+}
+
+// addVirtualLoss adds loss visits, with no score, to every node on the path. A
+// negative loss removes previously added virtual loss.
+func (p path) addVirtualLoss(loss int64) {
+	for _, e := range p {
+		e.node.visits += loss
+	}
+}
+
+// detach returns a childless, parentless node with the same state and policy
+// as the selected node, using the passed random source. Detached nodes can be
+// simulated from without touching the tree the original node belongs to. The
+// evaluation of the state is shared, so that evaluating the detached node also
+// evaluates the original, as are the statistics of adaptive playout policies,
+// which learn the actions of the path as those leading to the detached node.
+func (p path) detach(r *rand.Rand) *Node {
+	node := p.node()
+	// will not throw any error since we're already using a valid player count
+	n, _ := NewNode(node.numPlayers)
+	n.State = node.State
+	n.policy = node.policy
+	n.puct = &puctStats{eval: node.evaluation()}
+	n.rand = r
+	if stats := node.playoutStats(); stats != nil {
+		n.learning = &learning{stats: stats, path: p.actions()}
+	}
+	return &n
+}
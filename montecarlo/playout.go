@@ -0,0 +1,185 @@
+package montecarlo
+
+import (
+	"math"
+	"math/rand"
+)
+
+// PlayoutPolicy chooses the actions taken during the simulation phase of a
+// search (the playout), from the state reached so far. Policies accept one by
+// composition, see UCTPolicy; random playouts are used if none is given.
+type PlayoutPolicy interface {
+	// Choose returns the key of the next action to take from the passed state,
+	// which must be one of its legal actions (of which there is at least one).
+	// The state must not be modified. Any randomness should come from the
+	// passed random source, so that seeded searches are reproducible.
+	Choose(state State, legalActions ActionSet, r *rand.Rand) Key
+}
+
+// ActionWeight gives the weight of taking the action with the passed key from
+// the passed state, such as a heuristic estimate of how good it is for the
+// player to move. Higher weights are preferred.
+type ActionWeight func(state State, key Key) float64
+
+// UniformPlayout chooses every legal action with the same probability, giving
+// the light (random) playouts of plain MCTS.
+type UniformPlayout struct{}
+
+// Choose implements PlayoutPolicy.
+func (pp UniformPlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	key, _ := randomAction(r, legalActions)
+	return key
+}
+
+// EpsilonGreedyPlayout chooses the action with the highest heuristic weight,
+// except with probability Epsilon, in [0, 1], where it chooses uniformly at
+// random.
+type EpsilonGreedyPlayout struct {
+	Epsilon   float64
+	Heuristic ActionWeight
+}
+
+// NewEpsilonGreedyPlayout constructs an epsilon-greedy playout policy with the
+// passed exploration probability and heuristic.
+func NewEpsilonGreedyPlayout(epsilon float64, heuristic ActionWeight) EpsilonGreedyPlayout {
+	return EpsilonGreedyPlayout{
+		Epsilon:   epsilon,
+		Heuristic: heuristic,
+	}
+}
+
+// Choose implements PlayoutPolicy, ties between the best actions are broken
+// at random.
+func (pp EpsilonGreedyPlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	if pp.Heuristic == nil || r.Float64() < pp.Epsilon {
+		return UniformPlayout{}.Choose(state, legalActions, r)
+	}
+	return greedyAction(state, legalActions, pp.Heuristic, r)
+}
+
+// SoftmaxPlayout chooses actions with probability proportional to
+// exp(weight / Temperature), the Gibbs distribution over their weights. High
+// temperatures play more uniformly, and low temperatures more greedily; a
+// temperature that is not positive always chooses the highest weight.
+type SoftmaxPlayout struct {
+	Temperature float64
+	Weight      ActionWeight
+}
+
+// NewSoftmaxPlayout constructs a softmax playout policy with the passed
+// temperature and action weights.
+func NewSoftmaxPlayout(temperature float64, weight ActionWeight) SoftmaxPlayout {
+	return SoftmaxPlayout{
+		Temperature: temperature,
+		Weight:      weight,
+	}
+}
+
+// Choose implements PlayoutPolicy.
+func (pp SoftmaxPlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	if pp.Weight == nil {
+		return UniformPlayout{}.Choose(state, legalActions, r)
+	}
+	if pp.Temperature <= 0 {
+		return greedyAction(state, legalActions, pp.Weight, r)
+	}
+	keys := sortedActionKeys(legalActions)
+	weights := make([]float64, len(keys))
+	for i, k := range keys {
+		weights[i] = pp.Weight(state, k)
+	}
+	return keys[sampleIndex(r, gibbs(weights, pp.Temperature))]
+}
+
+// WinningMovePlayout takes any action that immediately wins the game for the
+// player to move: one leading to a terminal state in which that player has the
+// strictly highest score of all NumPlayers. Otherwise, the action is chosen by
+// the Fallback policy, or uniformly at random if it is nil.
+type WinningMovePlayout struct {
+	NumPlayers uint
+	Fallback   PlayoutPolicy
+}
+
+// NewWinningMovePlayout constructs a playout policy preferring winning moves in
+// a game of numPlayers players, and choosing other moves by the fallback.
+func NewWinningMovePlayout(numPlayers uint, fallback PlayoutPolicy) WinningMovePlayout {
+	return WinningMovePlayout{
+		NumPlayers: numPlayers,
+		Fallback:   fallback,
+	}
+}
+
+// Choose implements PlayoutPolicy, ties between winning actions are broken at
+// random.
+func (pp WinningMovePlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	player := state.Player()
+	winning := make([]Key, 0)
+	for _, k := range sortedActionKeys(legalActions) {
+		next := legalActions[k](state.Copy())
+		if len(next.LegalActions()) > 0 {
+			continue
+		}
+		if winner, ok := terminalWinner(next, pp.NumPlayers); ok && winner == player {
+			winning = append(winning, k)
+		}
+	}
+	if len(winning) > 0 {
+		return winning[r.Intn(len(winning))]
+	}
+	if pp.Fallback == nil {
+		return UniformPlayout{}.Choose(state, legalActions, r)
+	}
+	return pp.Fallback.Choose(state, legalActions, r)
+}
+
+// Cutoff implements PlayoutCutoff, passing it on to the fallback.
+func (pp WinningMovePlayout) Cutoff(state State, depth int) ([]float64, bool) {
+	return playoutCutoff(pp.Fallback, state, depth)
+}
+
+// greedyAction returns the key of the legal action with the highest weight,
+// ties are broken using the passed random source.
+func greedyAction(state State, legalActions ActionSet, weight ActionWeight, r *rand.Rand) Key {
+	keys := sortedActionKeys(legalActions)
+	weights := make([]float64, len(keys))
+	for i, k := range keys {
+		weights[i] = weight(state, k)
+	}
+	return keys[greedyIndex(r, weights)]
+}
+
+// greedyIndex returns the index of the highest of the passed values, ties are
+// broken using the passed random source.
+func greedyIndex(r *rand.Rand, values []float64) int {
+	max := math.Inf(-1)
+	maxima := make([]int, 0)
+	for i, v := range values {
+		if closeEnough(v, max) {
+			maxima = append(maxima, i)
+		} else if v > max || len(maxima) == 0 {
+			max = v
+			maxima = append(maxima[:0], i)
+		}
+	}
+	return maxima[r.Intn(len(maxima))]
+}
+
+// gibbs returns the probabilities of the Gibbs (softmax) distribution over the
+// passed weights at the passed temperature.
+func gibbs(weights []float64, temperature float64) []float64 {
+	// subtract the largest weight, to keep the exponentials finite
+	max := math.Inf(-1)
+	for _, w := range weights {
+		max = math.Max(max, w)
+	}
+	probabilities := make([]float64, len(weights))
+	total := float64(0)
+	for i, w := range weights {
+		probabilities[i] = math.Exp((w - max) / temperature)
+		total += probabilities[i]
+	}
+	for i := range probabilities {
+		probabilities[i] /= total
+	}
+	return probabilities
+}
@@ -0,0 +1,117 @@
+package montecarlo_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// firstPlayout always takes the legal action with the smallest key, counting
+// the actions it chooses
+type firstPlayout struct {
+	chosen *int
+}
+
+func (pp firstPlayout) Choose(state montecarlo.State, legalActions montecarlo.ActionSet, r *rand.Rand) montecarlo.Key {
+	*pp.chosen++
+	first := ""
+	for k := range legalActions {
+		if first == "" || k.(string) < first {
+			first = k.(string)
+		}
+	}
+	return first
+}
+
+// centre prefers the centre of the board, then the corners
+func centre(state montecarlo.State, key montecarlo.Key) float64 {
+	switch key.(string)[6:] {
+	case "1_1":
+		return 2
+	case "0_0", "0_2", "2_0", "2_2":
+		return 1
+	}
+	return 0
+}
+
+func TestPlayoutPolicyIsUsed(t *testing.T) {
+	makeActions()
+	chosen := 0
+	statePolicy = montecarlo.UCTPolicy{Playout: firstPlayout{&chosen}}
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai.SetRand(rand.New(rand.NewSource(1)))
+	if _, err := ai.Search(50, 1); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, chosen > 0)
+}
+
+func TestEpsilonGreedyPlayout(t *testing.T) {
+	makeActions()
+	state := initState()
+	r := rand.New(rand.NewSource(1))
+	greedy := montecarlo.NewEpsilonGreedyPlayout(0, centre)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "SET_X_1_1", greedy.Choose(state, state.LegalActions(), r))
+	}
+	// fully exploring plays every action
+	uniform := montecarlo.NewEpsilonGreedyPlayout(1, centre)
+	counts := make(map[montecarlo.Key]int)
+	for i := 0; i < 900; i++ {
+		counts[uniform.Choose(state, state.LegalActions(), r)]++
+	}
+	assert.Equal(t, 9, len(counts))
+}
+
+func TestSoftmaxPlayout(t *testing.T) {
+	makeActions()
+	state := initState()
+	r := rand.New(rand.NewSource(1))
+	softmax := montecarlo.NewSoftmaxPlayout(1, centre)
+	n := 20000
+	counts := make(map[montecarlo.Key]int)
+	for i := 0; i < n; i++ {
+		counts[softmax.Choose(state, state.LegalActions(), r)]++
+	}
+	// one centre, four corners and four edges
+	total := math.Exp(2) + 4*math.Exp(1) + 4
+	assert.InDelta(t, math.Exp(2)/total, float64(counts["SET_X_1_1"])/float64(n), 0.02)
+	assert.InDelta(t, math.Exp(1)/total, float64(counts["SET_X_0_0"])/float64(n), 0.02)
+	assert.InDelta(t, 1/total, float64(counts["SET_X_0_1"])/float64(n), 0.02)
+
+	// without a temperature the highest weight is always chosen
+	greedy := montecarlo.NewSoftmaxPlayout(0, centre)
+	assert.Equal(t, "SET_X_1_1", greedy.Choose(state, state.LegalActions(), r))
+}
+
+func TestWinningMovePlayout(t *testing.T) {
+	makeActions()
+	state := boardState(true,
+		"XX.",
+		"OO.",
+		"...",
+	)
+	r := rand.New(rand.NewSource(1))
+	chosen := 0
+	winning := montecarlo.NewWinningMovePlayout(2, firstPlayout{&chosen})
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "SET_X_0_2", winning.Choose(state, state.LegalActions(), r))
+	}
+	assert.Equal(t, 0, chosen)
+
+	// with no winning move the fallback chooses
+	state = boardState(true,
+		"X..",
+		"O..",
+		"...",
+	)
+	assert.Equal(t, "SET_X_0_1", winning.Choose(state, state.LegalActions(), r))
+	assert.Equal(t, 1, chosen)
+}
@@ -23,9 +23,115 @@ type BackpropPolicy interface {
 	Backpropagate(node *Node, score float64)
 }
 
+// BatchBackpropPolicy may be implemented by a BackpropPolicy which is able to
+// propagate the results of several simulations from the same node at once.
+type BatchBackpropPolicy interface {
+	// BackpropagateN propagates score, the total score of the given number of
+	// simulations, towards the root node.
+	BackpropagateN(node *Node, score float64, visits int64)
+}
+
+// AMAFPolicy may be implemented by a Policy which gathers all-moves-as-first
+// statistics (see RAVEPolicy). Its simulations report the actions they took,
+// so that they can be propagated along with the score.
+type AMAFPolicy interface {
+	// SimulateAMAF returns the score of a simulation from the passed node,
+	// along with the actions taken during the simulation.
+	SimulateAMAF(node *Node) (float64, []PlayedAction)
+	// BackpropagateAMAF propagates the score of a simulation, along with the
+	// actions taken during the simulation, towards the root node.
+	BackpropagateAMAF(node *Node, score float64, played []PlayedAction)
+}
+
+// ScoresPolicy may be implemented by a Policy which gathers statistics from
+// the final score of every player (see SimultaneousPolicy), rather than only
+// the score of a simulation.
+type ScoresPolicy interface {
+	// SimulateScores returns the score of a simulation from the passed node,
+	// along with the final score of every player.
+	SimulateScores(node *Node) (float64, []float64)
+	// BackpropagateScores propagates the score of a simulation, along with the
+	// final score of every player, towards the root node.
+	BackpropagateScores(node *Node, score float64, scores []float64)
+}
+
 // Policy is an interface containing all sub-policies required to define a MCTS.
 type Policy interface {
 	DefaultPolicy
 	TreePolicy
 	BackpropPolicy
 }
+
+// pathBackpropPolicy is implemented by the policies of this package, which
+// propagate results along the path an iteration took rather than along the
+// parents of the simulated node. The two differ for nodes shared between paths
+// (see Tree.EnableTranspositions).
+type pathBackpropPolicy interface {
+	// backpropagatePath propagates the result of the given number of
+	// simulations from the selected node along the passed path. The score of
+	// the result is their total score, its actions and final scores are only
+	// given for a single simulation.
+	backpropagatePath(p path, result Result, visits int64)
+}
+
+// backpropagateN propagates the total score of a number of simulations along
+// the passed path using the selected node's policy. Policies that are not a
+// BatchBackpropPolicy have the mean score propagated once per simulation.
+func backpropagateN(p path, score float64, visits int64) {
+	node := p.node()
+	if pp, ok := node.Policy().(pathBackpropPolicy); ok {
+		pp.backpropagatePath(p, Result{Score: score}, visits)
+		return
+	}
+	if bp, ok := node.Policy().(BatchBackpropPolicy); ok {
+		bp.BackpropagateN(node, score, visits)
+		return
+	}
+	for i := int64(0); i < visits; i++ {
+		node.Policy().Backpropagate(node, score/float64(visits))
+	}
+}
+
+// simulate simulates from the passed node using its policy. The actions taken
+// are included only if the policy is an AMAFPolicy, and the final score of
+// every player only if it is a ScoresPolicy.
+func simulate(node *Node) Result {
+	if ap, ok := node.Policy().(AMAFPolicy); ok {
+		score, played := ap.SimulateAMAF(node)
+		return Result{Score: score, Played: played}
+	}
+	if sp, ok := node.Policy().(ScoresPolicy); ok {
+		score, scores := sp.SimulateScores(node)
+		return Result{Score: score, Scores: scores}
+	}
+	return Result{Score: node.Policy().Simulate(node)}
+}
+
+// backpropagate propagates the result of a simulation along the passed path
+// using the selected node's policy.
+func backpropagate(p path, result Result) {
+	node := p.node()
+	if pp, ok := node.Policy().(pathBackpropPolicy); ok {
+		pp.backpropagatePath(p, result, 1)
+		return
+	}
+	if ap, ok := node.Policy().(AMAFPolicy); ok {
+		ap.BackpropagateAMAF(node, result.Score, result.Played)
+		return
+	}
+	if sp, ok := node.Policy().(ScoresPolicy); ok {
+		sp.BackpropagateScores(node, result.Score, result.Scores)
+		return
+	}
+	node.Policy().Backpropagate(node, result.Score)
+}
+
+// separateResults returns true if the results of simulations from the passed
+// node must be propagated one at a time, rather than as a batch.
+func separateResults(node *Node) bool {
+	switch node.Policy().(type) {
+	case AMAFPolicy, ScoresPolicy:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package montecarlo
+
+import "time"
+
+// Snapshot describes the state of a search that is still in progress.
+type Snapshot struct {
+	// BestKey is the key of the best action found so far.
+	BestKey Key
+	// Visits is the number of visits of each child of the root, by the key of
+	// the action leading to the child.
+	Visits map[Key]int64
+	// PrincipalVariation is the sequence of actions the search currently
+	// expects to be played, starting with BestKey.
+	PrincipalVariation []Key
+	// Iterations is the number of iterations the search has run.
+	Iterations int64
+	// Elapsed is the wall-clock time since the search started.
+	Elapsed time.Duration
+}
+
+// ProgressFunc is called with a snapshot of a search in progress. Returning
+// true stops the search early.
+type ProgressFunc func(snapshot Snapshot) bool
+
+// progressReporter decides when a search should report its progress. It is
+// shared by every copy of a MultiplayerMCTS, and reports nothing while it has
+// no callback.
+type progressReporter struct {
+	every    int64
+	interval time.Duration
+	callback ProgressFunc
+}
+
+// OnProgress registers a callback to be called during searches, every given
+// number of iterations and every given interval of time; a zero value for
+// either disables it. Only one callback is registered at a time, passing a nil
+// callback removes it.
+func (mcts MultiplayerMCTS) OnProgress(every int64, interval time.Duration, callback ProgressFunc) {
+	if mcts.progress == nil {
+		return
+	}
+	*mcts.progress = progressReporter{
+		every:    every,
+		interval: interval,
+		callback: callback,
+	}
+}
+
+// due returns true if progress should be reported after the passed number of
+// iterations, given the time of the last report. The time of the last report is
+// updated if so.
+func (pr *progressReporter) due(iterations int64, last *time.Time) bool {
+	if pr == nil || pr.callback == nil {
+		return false
+	}
+	now := time.Now()
+	if (pr.every > 0 && iterations%pr.every == 0) ||
+		(pr.interval > 0 && now.Sub(*last) >= pr.interval) {
+		*last = now
+		return true
+	}
+	return false
+}
+
+// snapshot takes a snapshot of the search tree, for a search that has run the
+// passed number of iterations since it started.
+func (mcts MultiplayerMCTS) snapshot(iterations int64, start time.Time) Snapshot {
+	root := mcts.tree.Root()
+	s := Snapshot{
+		Visits:     make(map[Key]int64, len(root.children)),
+		Iterations: iterations,
+		Elapsed:    time.Since(start),
+	}
+	for k, child := range root.children {
+		s.Visits[k] = child.Visits()
+	}
+	// follow the best child of each node from the root
+	for n := root; n != nil && !n.IsLeaf(); {
+		var key Key
+		key, n = n.selectFinalChild()
+		s.PrincipalVariation = append(s.PrincipalVariation, key)
+	}
+	if len(s.PrincipalVariation) > 0 {
+		s.BestKey = s.PrincipalVariation[0]
+	}
+	return s
+}
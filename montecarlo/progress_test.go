@@ -0,0 +1,101 @@
+package montecarlo_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestProgressEveryNIterations(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	var snapshots []montecarlo.Snapshot
+	ai.OnProgress(100, 0, func(s montecarlo.Snapshot) bool {
+		snapshots = append(snapshots, s)
+		return false
+	})
+	_, err = ai.Search(1000, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, 10, len(snapshots))
+	for i, s := range snapshots {
+		assert.Equal(t, int64(100*(i+1)), s.Iterations)
+		total := int64(0)
+		for _, v := range s.Visits {
+			total += v
+		}
+		assert.Equal(t, s.Iterations, total, "root children should account for every iteration")
+		assert.True(t, len(s.PrincipalVariation) > 0)
+		assert.Equal(t, s.PrincipalVariation[0], s.BestKey)
+		_, legal := initState().LegalActions()[s.BestKey]
+		assert.True(t, legal)
+	}
+}
+
+func TestProgressEveryInterval(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	reports := 0
+	ai.OnProgress(0, 5*time.Millisecond, func(s montecarlo.Snapshot) bool {
+		reports++
+		return false
+	})
+	_, err = ai.SearchContext(context.Background(), montecarlo.Budget{Duration: 50 * time.Millisecond}, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.True(t, reports > 1, "expected several timed reports")
+}
+
+func TestProgressStopsSearch(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	ai.OnProgress(50, 0, func(s montecarlo.Snapshot) bool {
+		return s.Iterations >= 300
+	})
+	_, err = ai.Search(1000, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, int64(300), ai.Tree().Root().Visits())
+}
+
+func TestProgressKeepsSearchDeterministic(t *testing.T) {
+	// reporting on a search must not consume its random source
+	search := func(report bool) montecarlo.SearchResult {
+		makeActions()
+		ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		ai.SetRand(rand.New(rand.NewSource(1)))
+		if report {
+			ai.OnProgress(10, 0, func(s montecarlo.Snapshot) bool {
+				return false
+			})
+		}
+		result, err := ai.Search(1000, 1/math.Sqrt2)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		return result
+	}
+	quiet, reported := search(false), search(true)
+	assert.Equal(t, quiet.BestKey, reported.BestKey)
+	assert.Equal(t, quiet.Children, reported.Children)
+}
@@ -0,0 +1,157 @@
+package montecarlo
+
+import (
+	"fmt"
+	"math"
+)
+
+// PriorEvaluator gives domain knowledge about states to a PUCTPolicy.
+type PriorEvaluator interface {
+	// Evaluate returns the prior probability of each legal action from the
+	// passed state being chosen (by the action's key), along with an estimate
+	// of the score a simulation from the state would give.
+	Evaluate(state State) (priors map[Key]float64, value float64)
+}
+
+// PUCTPolicy selects children by the PUCT formula, weighting exploration by the
+// prior probability of each action (Silver et al. 2017: Mastering the game of
+// Go without human knowledge - Nature, vol. 550).
+//
+// A node's state is evaluated when it is first simulated, and the next time the
+// node is selected a child is added for every legal action, with priors given
+// by that evaluation. Selection stops at any node that has not been evaluated
+// yet, even one visited by the virtual loss of a simulation in progress, so
+// evaluations only happen in Simulate, outside of any lock held by a
+// tree-parallel search. A BatchedEvaluator can then be used to evaluate the
+// states of concurrent simulations together.
+//
+// If UseValue is true, the evaluator's value estimate is used as the score of a
+// node instead of simulating a random playout from it.
+type PUCTPolicy struct {
+	Evaluator PriorEvaluator
+	UseValue  bool
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
+}
+
+// NewPUCTPolicy constructs a PUCT policy using the passed evaluator.
+func NewPUCTPolicy(evaluator PriorEvaluator, useValue bool) PUCTPolicy {
+	return PUCTPolicy{
+		Evaluator: evaluator,
+		UseValue:  useValue,
+	}
+}
+
+// puctValue gives the PUCT value of the passed child of node, from the point
+// of view of node's player. Children with no visits have a mean score of zero.
+func puctValue(node, child *Node, explorationParam float64) float64 {
+	visits := float64(child.Visits())
+	mean := float64(0)
+	if visits > 0 {
+		mean = child.Score(node.Player()) / visits
+	}
+	expl := math.Max(explorationParam, 0)
+	value := mean + expl*child.Prior()*math.Sqrt(float64(node.Visits()))/(1+visits)
+	if child.State != nil {
+		value += child.State.Bias()
+	}
+	return value
+}
+
+// evaluate evaluates the state of the passed node, unless it already has been.
+func (p PUCTPolicy) evaluate(node *Node) (map[Key]float64, float64) {
+	return node.evaluation().evaluate(p.Evaluator, node.State)
+}
+
+/******** IMPLEMENT Policy ********/
+
+// Select selects the child with the highest PUCT value, until a node that has
+// never been visited is found. Other nodes are expanded on the way down.
+func (p PUCTPolicy) Select(node *Node, explorationParam float64) *Node {
+	n := node
+	for n != nil && (n.IsRoot() || !n.IsTerminal()) {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if !n.IsExhausted() {
+			// simulate new nodes first, to evaluate them outside of the lock
+			// of any tree-parallel search, whatever their visits
+			if !n.evaluated() {
+				return n
+			}
+			p.Expand(n, explorationParam)
+		}
+		if n.IsLeaf() {
+			break
+		}
+		parent := n
+		_, n = parent.selectChildBy(func(child *Node) float64 {
+			return puctValue(parent, child, explorationParam)
+		})
+	}
+	return n
+}
+
+// Expand adds a child for every untried legal action from the passed node, with
+// prior probabilities given by the node's evaluation, and returns the node. If
+// the evaluator gives no priors, every action is given the same prior. The
+// node must already have been evaluated by Simulate.
+func (p PUCTPolicy) Expand(node *Node, explorationParam float64) *Node {
+	if !node.evaluated() {
+		panic(fmt.Sprintf("%v", UnevaluatedNode{node.State}))
+	}
+	priors, _ := p.evaluate(node)
+	legalActions := node.State.LegalActions()
+	for _, k := range sortedActionKeys(legalActions) {
+		if node.GetChild(k) != nil {
+			continue
+		}
+		n, err := NewNode(node.NumPlayers())
+		if err != nil {
+			panic(fmt.Sprintf("%v", err))
+		}
+		n.State = legalActions[k](node.State.Copy())
+		n.policy = n.State.Policy()
+		n.puct = &puctStats{prior: 1 / float64(len(legalActions))}
+		if len(priors) > 0 {
+			n.puct.prior = priors[k]
+		}
+		node.SetChild(k, &n)
+	}
+	return node
+}
+
+// Simulate evaluates the node, giving the evaluator's value estimate if
+// UseValue is true, or the score of the final state for terminal nodes.
+// Otherwise the score comes from a playout, as with the UCTPolicy.
+func (p PUCTPolicy) Simulate(node *Node) float64 {
+	if node.IsTerminal() {
+		if p.UseValue {
+			return node.State.Score(node.State.Player())
+		}
+		return UCTPolicy{Playout: p.Playout}.Simulate(node)
+	}
+	_, value := p.evaluate(node)
+	if !p.UseValue {
+		return UCTPolicy{Playout: p.Playout}.Simulate(node)
+	}
+	return value
+}
+
+// Backpropagate acts in exactly the same way as the UCTPolicy
+func (p PUCTPolicy) Backpropagate(node *Node, score float64) {
+	UCTPolicy{}.Backpropagate(node, score)
+}
+
+// BackpropagateN acts in exactly the same way as the UCTPolicy
+func (p PUCTPolicy) BackpropagateN(node *Node, score float64, visits int64) {
+	UCTPolicy{}.BackpropagateN(node, score, visits)
+}
+
+func (p PUCTPolicy) backpropagatePath(path path, result Result, visits int64) {
+	UCTPolicy{}.backpropagatePath(path, result, visits)
+}
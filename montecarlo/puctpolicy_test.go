@@ -0,0 +1,111 @@
+package montecarlo_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// favouringEvaluator gives a high prior to a single action, spreading the rest
+// evenly, and values every state at 0.5
+type favouringEvaluator struct {
+	favourite montecarlo.Key
+	lock      sync.Mutex
+	calls     int
+}
+
+func (e *favouringEvaluator) Evaluate(state montecarlo.State) (map[montecarlo.Key]float64, float64) {
+	e.lock.Lock()
+	e.calls++
+	e.lock.Unlock()
+	legal := state.LegalActions()
+	priors := make(map[montecarlo.Key]float64, len(legal))
+	for k := range legal {
+		if k == e.favourite {
+			priors[k] = 0.9
+		} else {
+			priors[k] = 0.1 / float64(len(legal)-1)
+		}
+	}
+	return priors, 0.5
+}
+
+func TestPUCTSearch(t *testing.T) {
+	makeActions()
+	evaluator := &favouringEvaluator{favourite: "SET_X_1_1"}
+	statePolicy = montecarlo.NewPUCTPolicy(evaluator, true)
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(200, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := ai.Tree().Root()
+	// the root is expanded all at once
+	assert.Equal(t, len(initState().LegalActions()), len(result.Children))
+	assert.InDelta(t, 0.9, root.GetChild("SET_X_1_1").Prior(), 0.000001)
+	// with equal values everywhere, visits should follow the priors
+	most := result.Children[0]
+	for _, c := range result.Children {
+		if c.Visits > most.Visits {
+			most = c
+		}
+	}
+	assert.Equal(t, montecarlo.Key("SET_X_1_1"), most.Key)
+	// every visited node is evaluated exactly once
+	assert.Equal(t, countEvaluated(root), evaluator.calls)
+}
+
+// countEvaluated counts the visited, non-terminal nodes of a tree
+func countEvaluated(node *montecarlo.Node) int {
+	if node.Visits() == 0 || node.IsTerminal() {
+		return 0
+	}
+	evaluated := 1
+	for k := range node.State.LegalActions() {
+		if c := node.GetChild(k); c != nil {
+			evaluated += countEvaluated(c)
+		}
+	}
+	return evaluated
+}
+
+func TestPUCTWithPlayouts(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewPUCTPolicy(&favouringEvaluator{favourite: "SET_X_0_0"}, false)
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(300, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal)
+	assert.Equal(t, int64(300), result.Iterations)
+}
+
+func TestPUCTSelectUnevaluated(t *testing.T) {
+	makeActions()
+	evaluator := &favouringEvaluator{favourite: "SET_X_1_1"}
+	policy := montecarlo.NewPUCTPolicy(evaluator, true)
+	root, err := montecarlo.NewNode(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.State = initState()
+	// visits such as the virtual loss of a tree-parallel simulation in progress
+	// don't make an unevaluated node expandable
+	root.AddVisits(3)
+	assert.Equal(t, &root, policy.Select(&root, 1))
+	assert.True(t, root.IsLeaf())
+	assert.Equal(t, 0, evaluator.calls)
+	assert.Panics(t, func() { policy.Expand(&root, 1) })
+}
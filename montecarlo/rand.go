@@ -0,0 +1,85 @@
+package montecarlo
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultRand is used by nodes in trees that have not been given their own
+// random source. It is safe for concurrent use.
+var defaultRand = rand.New(&lockedSource{
+	src: rand.NewSource(time.Now().UTC().UnixNano()),
+})
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	lock sync.Mutex
+	src  rand.Source
+}
+
+func (ls *lockedSource) Int63() int64 {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	return ls.src.Int63()
+}
+
+func (ls *lockedSource) Seed(seed int64) {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	ls.src.Seed(seed)
+}
+
+// newChildRand creates a new random source seeded from the passed one, so that
+// a reproducible sequence of random sources can be handed out to workers.
+func newChildRand(r *rand.Rand) *rand.Rand {
+	return rand.New(rand.NewSource(r.Int63()))
+}
+
+// keyList is an array of keys which can be sorted, so that maps keyed by Key
+// can be iterated over in a reproducible order.
+type keyList []Key
+
+/*-------- IMPLEMENT sort.Interface --------*/
+
+func (kl keyList) Swap(i, j int) {
+	kl[i], kl[j] = kl[j], kl[i]
+}
+
+func (kl keyList) Len() int {
+	return len(kl)
+}
+
+func (kl keyList) Less(i, j int) bool {
+	return keyString(kl[i]) < keyString(kl[j])
+}
+
+// keyString gives the string a key is sorted by.
+func keyString(k Key) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", k)
+}
+
+// sortedActionKeys returns the keys of the passed actions in sorted order.
+func sortedActionKeys(actions ActionSet) keyList {
+	keys := make(keyList, 0, len(actions))
+	for k := range actions {
+		keys = append(keys, k)
+	}
+	sort.Stable(keys)
+	return keys
+}
+
+// sortedChildKeys returns the keys of the passed children in sorted order.
+func sortedChildKeys(children map[Key]*Node) keyList {
+	keys := make(keyList, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+	sort.Stable(keys)
+	return keys
+}
@@ -0,0 +1,67 @@
+package montecarlo_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// rootVisits returns the visits of each child of the root of the passed tree
+func rootVisits(tree *montecarlo.Tree) map[montecarlo.Key]int64 {
+	visits := make(map[montecarlo.Key]int64)
+	for k := range tree.Root().State.LegalActions() {
+		if c := tree.Root().GetChild(k); c != nil {
+			visits[k] = c.Visits()
+		}
+	}
+	return visits
+}
+
+// seededSearch runs a search with a random source seeded with the passed seed
+func seededSearch(t *testing.T, seed int64, search func(ai montecarlo.MultiplayerMCTS) montecarlo.Key) (montecarlo.Key, *montecarlo.Tree) {
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai.SetRand(rand.New(rand.NewSource(seed)))
+	return search(ai), ai.Tree()
+}
+
+func TestSeededSearchesAreReproducible(t *testing.T) {
+	makeActions()
+	searches := map[string]func(ai montecarlo.MultiplayerMCTS) montecarlo.Key{
+		"single": func(ai montecarlo.MultiplayerMCTS) montecarlo.Key {
+			result, _ := ai.Search(500, 1/math.Sqrt2)
+			return result.BestKey
+		},
+		"root": func(ai montecarlo.MultiplayerMCTS) montecarlo.Key {
+			result, _ := ai.RootParallelSearch(3, 200, 1/math.Sqrt2)
+			return result.BestKey
+		},
+		"leaf": func(ai montecarlo.MultiplayerMCTS) montecarlo.Key {
+			result, _ := ai.LeafParallelSearch(3, 100, 1/math.Sqrt2)
+			return result.BestKey
+		},
+	}
+	for name, search := range searches {
+		key1, tree1 := seededSearch(t, 42, search)
+		key2, tree2 := seededSearch(t, 42, search)
+		assert.Equal(t, key1, key2, name+": identical seeds should pick identical moves")
+		assert.Equal(t, tree1.NumNodes(), tree2.NumNodes(), name+": identical seeds should grow identical trees")
+		assert.Equal(t, rootVisits(tree1), rootVisits(tree2), name+": identical seeds should grow identical trees")
+	}
+}
+
+func TestSeededSearchesDiffer(t *testing.T) {
+	makeActions()
+	search := func(ai montecarlo.MultiplayerMCTS) montecarlo.Key {
+		result, _ := ai.Search(200, 1/math.Sqrt2)
+		return result.BestKey
+	}
+	_, tree1 := seededSearch(t, 1, search)
+	_, tree2 := seededSearch(t, 2, search)
+	assert.NotEqual(t, rootVisits(tree1), rootVisits(tree2), "different seeds should grow different trees")
+}
@@ -0,0 +1,161 @@
+package montecarlo
+
+import "math"
+
+// BetaSchedule gives the weight of a child's all-moves-as-first (AMAF) value
+// against its UCT value, given the number of visits of the child and the number
+// of AMAF visits of the action leading to it. The weight should be in the range
+// [0, 1].
+type BetaSchedule func(visits, amafVisits int64) float64
+
+// HandSelectedSchedule is the schedule beta = sqrt(k / (3n + k)), where k is
+// the number of visits at which the AMAF and UCT values are given equal weight
+// (Gelly & Silver 2007: Combining Online and Offline Knowledge in UCT).
+func HandSelectedSchedule(equivalence float64) BetaSchedule {
+	return func(visits, amafVisits int64) float64 {
+		return math.Sqrt(equivalence / (3*float64(visits) + equivalence))
+	}
+}
+
+// MinimumMSESchedule is the schedule beta = m / (n + m + 4b^2nm), where n is
+// the number of visits, m the number of AMAF visits, and b the bias of AMAF
+// values (Gelly & Silver 2011: Monte-Carlo tree search and rapid action value
+// estimation in computer Go - Artificial Intelligence, vol. 175, no. 11).
+func MinimumMSESchedule(bias float64) BetaSchedule {
+	return func(visits, amafVisits int64) float64 {
+		n := float64(visits)
+		m := float64(amafVisits)
+		return m / (n + m + 4*bias*bias*n*m)
+	}
+}
+
+// RAVEPolicy is an extension of the UCTPolicy, based on rapid action value
+// estimation (Gelly & Silver 2011). Each node records all-moves-as-first (AMAF)
+// statistics: the score of every action its player took at any point after
+// the node, during every iteration through it. Children are selected by
+// blending their UCT value with the AMAF value of the action leading to them,
+// weighted by Beta.
+type RAVEPolicy struct {
+	Beta BetaSchedule
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
+}
+
+// NewRAVEPolicy constructs a RAVE policy using the hand-selected schedule with
+// the passed equivalence parameter.
+func NewRAVEPolicy(equivalence float64) RAVEPolicy {
+	return RAVEPolicy{
+		Beta: HandSelectedSchedule(equivalence),
+	}
+}
+
+// raveValue blends the UCT value of the passed child of node with the AMAF
+// value of the action leading to it, from the point of view of node's player.
+// The AMAF statistics are those of amafNode, which is either node or one of its
+// ancestors. The exploration term and any bias are added on top.
+func raveValue(node, amafNode, child *Node, beta BetaSchedule, explorationParam float64) float64 {
+	visits := child.Visits()
+	if visits <= 0 {
+		// all nodes with no visits will have an equal chance (positive infinity)
+		return math.Inf(1)
+	}
+	player := node.Player()
+	value := child.Score(player) / float64(visits)
+	if amafVisits := amafNode.AMAFVisits(child.Key()); amafVisits > 0 {
+		b := beta(visits, amafVisits)
+		amaf := amafNode.AMAFScore(child.Key(), player) / float64(amafVisits)
+		value = (1-b)*value + b*amaf
+	}
+	expl := math.Max(explorationParam, 0)
+	value += expl * math.Sqrt(float64(2)*math.Log(float64(node.Visits()))/float64(visits))
+	if child.State != nil {
+		value += child.State.Bias()
+	}
+	return value
+}
+
+// beta returns the policy's schedule, or the hand-selected schedule with an
+// equivalence parameter of 1000 if none was given.
+func (p RAVEPolicy) beta() BetaSchedule {
+	if p.Beta == nil {
+		return HandSelectedSchedule(1000)
+	}
+	return p.Beta
+}
+
+/******** IMPLEMENT Policy ********/
+
+// Select selects the child with the highest blended RAVE value
+func (p RAVEPolicy) Select(node *Node, explorationParam float64) *Node {
+	beta := p.beta()
+	n := node
+	for n != nil && (n.IsRoot() || !n.IsTerminal()) {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if !n.IsExhausted() {
+			return p.Expand(n, explorationParam)
+		}
+		if n.IsLeaf() {
+			// a terminal root
+			break
+		}
+		parent := n
+		_, n = parent.selectChildBy(func(child *Node) float64 {
+			return raveValue(parent, parent, child, beta, explorationParam)
+		})
+	}
+	return n
+}
+
+// Expand acts in exactly the same way as the UCTPolicy
+func (p RAVEPolicy) Expand(node *Node, explorationParam float64) *Node {
+	return UCTPolicy{}.Expand(node, explorationParam)
+}
+
+// Simulate acts in exactly the same way as the UCTPolicy
+func (p RAVEPolicy) Simulate(node *Node) float64 {
+	score, _ := p.SimulateAMAF(node)
+	return score
+}
+
+// Backpropagate acts in the same way as BackpropagateAMAF, for a simulation
+// that took no actions.
+func (p RAVEPolicy) Backpropagate(node *Node, score float64) {
+	p.BackpropagateAMAF(node, score, nil)
+}
+
+/******** IMPLEMENT AMAFPolicy ********/
+
+// SimulateAMAF selects legal moves with the playout policy until the end of
+// the simulation is reached, recording each action taken.
+func (p RAVEPolicy) SimulateAMAF(node *Node) (float64, []PlayedAction) {
+	state, played := playout(node, p.Playout)
+	return state.Score(state.Player()), played
+}
+
+// BackpropagateAMAF propagates the same score up the tree until the root is
+// reached, in the same way as the UCTPolicy. The AMAF statistics of each node
+// on the way are updated with every action taken after it: the actions of the
+// simulation, and the actions leading from it to the simulated node.
+func (p RAVEPolicy) BackpropagateAMAF(node *Node, score float64, played []PlayedAction) {
+	p.backpropagatePath(parentPath(node), Result{Score: score, Played: played}, 1)
+}
+
+func (p RAVEPolicy) backpropagatePath(path path, result Result, visits int64) {
+	player := path.node().Player()
+	// copy, since the actions leading to each node are added on the way up
+	after := append(make([]PlayedAction, 0, len(result.Played)), result.Played...)
+	for _, e := range path {
+		e.node.SetScore(player, e.node.Score(player)+result.Score)
+		e.node.addVisitsFrom(e.parent, visits)
+		e.node.addAMAF(after, player, result.Score)
+		if e.parent != nil {
+			after = append(after, PlayedAction{e.parent.Player(), e.key})
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package montecarlo_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestHandSelectedSchedule(t *testing.T) {
+	beta := montecarlo.HandSelectedSchedule(100)
+	assert.Equal(t, float64(1), beta(0, 10))
+	// equal weight is reached at k visits
+	assert.InDelta(t, 0.5, beta(100, 10), 0.000001)
+	assert.True(t, beta(1000, 10) < beta(100, 10), "beta should decrease with visits")
+}
+
+func TestMinimumMSESchedule(t *testing.T) {
+	beta := montecarlo.MinimumMSESchedule(0)
+	assert.InDelta(t, 0.25, beta(30, 10), 0.000001)
+	assert.True(t, montecarlo.MinimumMSESchedule(0.1)(30, 10) < beta(30, 10), "bias should lower beta")
+}
+
+func TestRAVESearch(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewRAVEPolicy(100)
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai.SetRand(rand.New(rand.NewSource(7)))
+	result, err := ai.Search(500, 1/math.Sqrt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal)
+	root := ai.Tree().Root()
+	for _, c := range result.Children {
+		// every visit of a child is also an AMAF visit of its action
+		assert.True(t, root.AMAFVisits(c.Key) >= c.Visits, "AMAF visits should include the child's own visits")
+		assert.True(t, root.AMAFScore(c.Key, root.Player()) >= root.GetChild(c.Key).Score(root.Player()))
+	}
+	// the opponent's actions are never counted for the root's player
+	for k := range initState().LegalActions() {
+		opponent := "SET_O" + k.(string)[len("SET_X"):]
+		assert.Equal(t, int64(0), root.AMAFVisits(opponent))
+	}
+}
+
+func TestRAVESearchTerminalRoot(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewRAVEPolicy(100)
+	defer func() { statePolicy = nil }()
+	state := boardState(false,
+		"XXX",
+		"OO.",
+		"...",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(10, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), result.Iterations)
+}
+
+func TestRAVEParallelSearch(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewRAVEPolicy(100)
+	defer func() { statePolicy = nil }()
+	for _, strategy := range []montecarlo.Strategy{montecarlo.RootParallel{}, montecarlo.LeafParallel{}, montecarlo.TreeParallel{VirtualLoss: 1}} {
+		ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ai.ParallelSearch(strategy, montecarlo.LocalWorkers(2), 50, 1/math.Sqrt2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := ai.Tree().Root()
+		amaf := int64(0)
+		for k := range initState().LegalActions() {
+			amaf += root.AMAFVisits(k)
+		}
+		assert.True(t, amaf >= root.Visits(), "parallel searches should gather AMAF statistics")
+	}
+}
+
+func TestGRAVESearch(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewGRAVEPolicy(20, 0.01)
+	defer func() { statePolicy = nil }()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(300, 1/math.Sqrt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, legal := initState().LegalActions()[result.BestKey]
+	assert.True(t, legal)
+	assert.Equal(t, int64(300), result.Iterations)
+}
+
+func TestGRAVESearchTerminalRoot(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.NewGRAVEPolicy(50, 0.01)
+	defer func() { statePolicy = nil }()
+	state := boardState(false,
+		"XXX",
+		"OO.",
+		"...",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(10, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), result.Iterations)
+}
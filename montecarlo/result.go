@@ -0,0 +1,84 @@
+package montecarlo
+
+import "time"
+
+// ChildStats describes a child of the root of a search tree, after a search.
+type ChildStats struct {
+	// Key is the key of the action leading to the child.
+	Key Key
+	// Visits is the number of visits the child has had.
+	Visits int64
+	// MeanScore is the mean score of each player over the child's visits, or
+	// the probability weighted mean score of its outcomes for chance nodes.
+	MeanScore []float64
+	// UCB is the upper confidence bound of the child, for the root's player
+	// and the search's exploration parameter.
+	UCB float64
+	// Exact is the final score of each player from the child, if it has been
+	// proven (see ScoreBoundedPolicy), or nil otherwise.
+	Exact []float64
+}
+
+// SearchResult describes the outcome of a search.
+type SearchResult struct {
+	// BestKey is the key of the best action to take.
+	BestKey Key
+	// BestAction is the best action to take (according to the list of possible
+	// actions).
+	BestAction *Action
+	// Children describes every child of the root, in order of key.
+	Children []ChildStats
+	// Iterations is the number of simulations that were propagated to the
+	// root during the search.
+	Iterations int64
+	// Elapsed is the wall-clock time the search took.
+	Elapsed time.Duration
+	// MaxDepth is the depth of the deepest node in the tree, the root having a
+	// depth of zero.
+	MaxDepth int
+	// Nodes is the number of nodes in the tree.
+	Nodes int
+	// Workers contains the statistics of each worker, for parallel searches.
+	Workers []WorkerStats
+	// Exact is the final score of each player from the root, if it has been
+	// proven (see ScoreBoundedPolicy), or nil otherwise.
+	Exact []float64
+	// PlayerStats contains the statistics of each player's actions, indexed by
+	// player, if the root is a simultaneous-move node (see SimultaneousPolicy).
+	PlayerStats [][]ActionStats
+}
+
+// result describes the tree of this MCTS after a search which started at the
+// passed time, when the root had the passed number of visits.
+func (mcts MultiplayerMCTS) result(expl float64, startVisits int64, start time.Time) SearchResult {
+	root := mcts.tree.Root()
+	key, action := mcts.bestAction()
+	r := SearchResult{
+		BestKey:     key,
+		BestAction:  action,
+		Children:    make([]ChildStats, 0, len(root.children)),
+		Iterations:  root.Visits() - startVisits,
+		Elapsed:     time.Since(start),
+		MaxDepth:    root.height(),
+		Nodes:       root.Size(),
+		Exact:       root.Exact(),
+		PlayerStats: copyJoint(root.joint.playerStats()),
+	}
+	for _, k := range sortedChildKeys(root.children) {
+		child := root.children[k]
+		mean := make([]float64, child.NumPlayers())
+		if child.Visits() > 0 {
+			for p := range mean {
+				mean[p] = child.expectedScore(uint(p))
+			}
+		}
+		r.Children = append(r.Children, ChildStats{
+			Key:       k,
+			Visits:    child.Visits(),
+			MeanScore: mean,
+			UCB:       child.UpperConfidenceBound(expl, root.Player()),
+			Exact:     child.Exact(),
+		})
+	}
+	return r
+}
@@ -0,0 +1,64 @@
+package montecarlo_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestSearchResult(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.Search(500, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, int64(500), result.Iterations)
+	assert.Equal(t, ai.Tree().NumNodes(), result.Nodes)
+	assert.True(t, result.MaxDepth > 0 && result.MaxDepth <= 9, "tictactoe lasts at most 9 moves")
+	assert.True(t, result.Elapsed > 0)
+	assert.Nil(t, result.Workers)
+	assert.Equal(t, len(initState().LegalActions()), len(result.Children))
+	visits := int64(0)
+	best := false
+	for i, c := range result.Children {
+		if i > 0 {
+			assert.True(t, fmt.Sprint(result.Children[i-1].Key) < fmt.Sprint(c.Key), "children should be ordered by key")
+		}
+		visits += c.Visits
+		assert.Equal(t, 2, len(c.MeanScore))
+		for _, mean := range c.MeanScore {
+			assert.True(t, mean >= 0 && mean <= 1)
+		}
+		best = best || c.Key == result.BestKey
+	}
+	assert.Equal(t, int64(500), visits)
+	assert.True(t, best, "the best key should be one of the root's children")
+	// a further search should only count its own iterations
+	result, err = ai.Search(100, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, int64(100), result.Iterations)
+}
+
+func TestParallelSearchResult(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	result, err := ai.RootParallelSearch(2, 100, 1/math.Sqrt2)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+	assert.Equal(t, int64(200), result.Iterations)
+	assert.Equal(t, 2, len(result.Workers))
+	assert.Equal(t, ai.Tree().NumNodes(), result.Nodes)
+}
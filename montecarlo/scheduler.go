@@ -0,0 +1,146 @@
+package montecarlo
+
+import (
+	"sync"
+	"time"
+)
+
+// WorkerStats describes the work done by a single worker during a parallel
+// search.
+type WorkerStats struct {
+	// Worker is the index of the worker, in the range [0, number of workers).
+	Worker int
+	// Iterations is the number of iterations (select, simulate, backpropagate)
+	// or simulations the worker ran.
+	Iterations int64
+	// Nodes is the number of nodes in the subtrees the worker searched, zero
+	// for workers that only ran simulations.
+	Nodes int
+	// Elapsed is the wall-clock time the worker spent working.
+	Elapsed time.Duration
+}
+
+// Scheduler hands jobs out to a pool of workers, each worker is driven by its
+// own goroutine.
+type Scheduler struct {
+	workers  []Worker
+	requests chan request
+	// direct holds a channel of requests for each worker, that no other
+	// worker may take
+	direct  []chan request
+	stats   []WorkerStats
+	running sync.WaitGroup
+}
+
+// request is a job along with where its result should be sent.
+type request struct {
+	job    Job
+	index  int
+	result chan<- indexedResult
+}
+
+// indexedResult is a result along with the index of the job that produced it.
+type indexedResult struct {
+	result Result
+	index  int
+}
+
+// NewScheduler creates a scheduler and starts a goroutine for each worker. The
+// scheduler must be closed when it is no longer needed.
+func NewScheduler(workers []Worker) *Scheduler {
+	s := &Scheduler{
+		workers:  workers,
+		requests: make(chan request),
+		direct:   make([]chan request, len(workers)),
+		stats:    make([]WorkerStats, len(workers)),
+	}
+	s.running.Add(len(workers))
+	for i := range workers {
+		s.direct[i] = make(chan request)
+		s.stats[i].Worker = i
+		go s.drive(i)
+	}
+	return s
+}
+
+// NumWorkers returns the number of workers in the pool.
+func (s *Scheduler) NumWorkers() int {
+	return len(s.workers)
+}
+
+// Do hands the passed jobs out to the pool and waits for all of them to
+// finish. The results are returned in the same order as the jobs. Do may be
+// called concurrently.
+func (s *Scheduler) Do(jobs ...Job) []Result {
+	out := make(chan indexedResult, len(jobs))
+	for i, job := range jobs {
+		s.requests <- request{job, i, out}
+	}
+	return collect(out, len(jobs))
+}
+
+// DoEach hands the job at each index to the worker with the same index, and
+// waits for all of them to finish. There must be no more jobs than workers. The
+// results are returned in the same order as the jobs. DoEach may be called
+// concurrently.
+func (s *Scheduler) DoEach(jobs ...Job) []Result {
+	out := make(chan indexedResult, len(jobs))
+	for i, job := range jobs {
+		s.direct[i] <- request{job, i, out}
+	}
+	return collect(out, len(jobs))
+}
+
+// collect receives n indexed results, and orders them by index.
+func collect(out <-chan indexedResult, n int) []Result {
+	results := make([]Result, n)
+	for i := 0; i < n; i++ {
+		r := <-out
+		results[r.index] = r.result
+	}
+	return results
+}
+
+// Close stops all of the workers' goroutines, and returns the statistics of
+// each worker. Do must not be called after Close.
+func (s *Scheduler) Close() []WorkerStats {
+	close(s.requests)
+	s.running.Wait()
+	return s.stats
+}
+
+// drive passes jobs to the worker with the given index until the scheduler is
+// closed.
+func (s *Scheduler) drive(i int) {
+	defer s.running.Done()
+	for {
+		select {
+		case req, ok := <-s.requests:
+			if !ok {
+				return
+			}
+			s.work(i, req)
+		case req := <-s.direct[i]:
+			s.work(i, req)
+		}
+	}
+}
+
+// work has the worker with the given index carry out a request, and records
+// its statistics.
+func (s *Scheduler) work(i int, req request) {
+	stats := &s.stats[i]
+	start := time.Now()
+	result := s.workers[i].Work(req.job)
+	stats.Elapsed += time.Since(start)
+	switch req.job.Kind {
+	case SimulationJob:
+		stats.Iterations++
+	case SubtreeJob:
+		stats.Iterations += req.job.Iterations
+		if result.Tree != nil {
+			stats.Nodes += result.Tree.NumNodes()
+		}
+	}
+	req.result <- indexedResult{result, req.index}
+}
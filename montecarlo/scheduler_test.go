@@ -0,0 +1,55 @@
+package montecarlo
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// echoWorker scores each job with the job's number of iterations
+type echoWorker struct{}
+
+func (w echoWorker) Work(job Job) Result {
+	return Result{Score: float64(job.Iterations)}
+}
+
+func TestSchedulerDoPreservesOrder(t *testing.T) {
+	scheduler := NewScheduler([]Worker{echoWorker{}, echoWorker{}, echoWorker{}})
+	jobs := make([]Job, 50)
+	for i := range jobs {
+		jobs[i] = Job{Kind: SimulationJob, Iterations: int64(i)}
+	}
+	results := scheduler.Do(jobs...)
+	stats := scheduler.Close()
+	assert.Equal(t, len(jobs), len(results))
+	for i, r := range results {
+		assert.Equal(t, float64(i), r.Score)
+	}
+	total := int64(0)
+	for i, s := range stats {
+		assert.Equal(t, i, s.Worker)
+		total += s.Iterations
+	}
+	assert.Equal(t, int64(len(jobs)), total)
+}
+
+func TestLocalWorkerUnknownJobKind(t *testing.T) {
+	result := LocalWorker{}.Work(Job{Kind: JobKind(-1)})
+	_, ok := result.Err.(UnknownJobKind)
+	assert.True(t, ok, "expected UnknownJobKind error")
+}
+
+func TestSchedulerDoEach(t *testing.T) {
+	scheduler := NewScheduler([]Worker{echoWorker{}, echoWorker{}, echoWorker{}})
+	results := scheduler.DoEach(
+		Job{Kind: SubtreeJob, Iterations: 1},
+		Job{Kind: SubtreeJob, Iterations: 2},
+		Job{Kind: SubtreeJob, Iterations: 3},
+	)
+	stats := scheduler.Close()
+	for i, r := range results {
+		assert.Equal(t, float64(i+1), r.Score)
+		// each worker should have been given the job at its own index
+		assert.Equal(t, int64(i+1), stats[i].Iterations)
+	}
+}
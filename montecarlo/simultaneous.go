@@ -0,0 +1,320 @@
+package montecarlo
+
+import "fmt"
+
+// SimultaneousState is a State in which every player acts at once, each
+// choosing from their own set of actions. The joint action made up of every
+// player's choice is one of the state's legal actions.
+type SimultaneousState interface {
+	State
+	// PlayerActions returns the keys of the actions each player may choose
+	// from, indexed by player. Players with no actions do not act.
+	PlayerActions() [][]Key
+	// JointAction returns the key of the legal action made up of the passed
+	// choice of each player, indexed by player. Players that do not act are
+	// given a nil choice.
+	JointAction(choices []Key) Key
+}
+
+// SimultaneousPolicy is the UCTPolicy extended with decoupled selection at
+// simultaneous-move nodes (see SimultaneousState), as in Decoupled UCT
+// (Lanctot et al. 2013: Monte Carlo Tree Search in Simultaneous Move Games
+// with Applications to Goofspiel - Computer Games Workshop at IJCAI 2013).
+//
+// At a simultaneous-move node each player chooses their own action with the
+// Bandit, knowing only their own statistics, and the joint action made up of
+// those choices is taken. During backpropagation, every player's chosen action
+// is updated with that player's final score. Other nodes are treated exactly
+// as by the UCTPolicy.
+type SimultaneousPolicy struct {
+	Bandit Bandit
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
+}
+
+// NewSimultaneousPolicy constructs a simultaneous-move policy choosing actions
+// with the passed bandit.
+func NewSimultaneousPolicy(bandit Bandit) SimultaneousPolicy {
+	return SimultaneousPolicy{
+		Bandit: bandit,
+	}
+}
+
+// bandit returns the bandit of this policy, DecoupledUCB by default.
+func (p SimultaneousPolicy) bandit() Bandit {
+	if p.Bandit == nil {
+		return DecoupledUCB{}
+	}
+	return p.Bandit
+}
+
+// PlayerStats returns the statistics of each of the passed player's actions,
+// if this node is a simultaneous-move node that has been selected from, or nil
+// otherwise.
+func (node Node) PlayerStats(player uint) []ActionStats {
+	if node.joint == nil || int(player) >= len(node.joint.players) {
+		return nil
+	}
+	return append([]ActionStats(nil), node.joint.players[player]...)
+}
+
+// simultaneous returns the state of this node as a SimultaneousState if it is
+// one in which any player acts.
+func (node Node) simultaneous() (SimultaneousState, bool) {
+	ss, ok := node.State.(SimultaneousState)
+	if !ok {
+		return nil, false
+	}
+	for _, actions := range ss.PlayerActions() {
+		if len(actions) > 0 {
+			return ss, true
+		}
+	}
+	return nil, false
+}
+
+// jointStats returns the statistics of every player's actions at this node,
+// creating them if needed.
+func (node *Node) jointStats(ss SimultaneousState) [][]ActionStats {
+	if node.joint == nil {
+		node.joint = &simultaneousStats{}
+	}
+	if node.joint.players == nil {
+		playerActions := ss.PlayerActions()
+		node.joint.players = make([][]ActionStats, len(playerActions))
+		for p, actions := range playerActions {
+			node.joint.players[p] = make([]ActionStats, len(actions))
+			for i, k := range actions {
+				node.joint.players[p][i].Key = k
+			}
+		}
+	}
+	return node.joint.players
+}
+
+// simultaneousStats are what SimultaneousPolicy keeps for a node: the
+// statistics of every player's actions if it is a simultaneous-move node, and
+// the index of each player's action in the statistics of the parent for the
+// joint action leading to it.
+type simultaneousStats struct {
+	players [][]ActionStats
+	picks   []int
+}
+
+// playerStats returns the statistics of every player's actions, nil if this
+// is not a simultaneous-move node.
+func (s *simultaneousStats) playerStats() [][]ActionStats {
+	if s == nil {
+		return nil
+	}
+	return s.players
+}
+
+// copy returns an independent copy of the statistics.
+func (s *simultaneousStats) copy() *simultaneousStats {
+	if s == nil {
+		return nil
+	}
+	return &simultaneousStats{
+		players: copyJoint(s.players),
+		picks:   append([]int(nil), s.picks...),
+	}
+}
+
+// merge adds the statistics of every player's actions from other to these,
+// and returns the result, which is a copy of other if these are nil.
+func (s *simultaneousStats) merge(other *simultaneousStats) *simultaneousStats {
+	if s == nil {
+		return other.copy()
+	}
+	if other == nil {
+		return s
+	}
+	if s.picks == nil {
+		s.picks = append([]int(nil), other.picks...)
+	}
+	if s.players == nil {
+		s.players = copyJoint(other.players)
+		return s
+	}
+	for p := range s.players {
+		if p >= len(other.players) {
+			break
+		}
+		for i := range s.players[p] {
+			for _, o := range other.players[p] {
+				if o.Key == s.players[p][i].Key {
+					s.players[p][i].Visits += o.Visits
+					s.players[p][i].Score += o.Score
+					s.players[p][i].Estimate += o.Estimate
+				}
+			}
+		}
+	}
+	return s
+}
+
+// bare returns the picks alone, which belong to the joint action leading to
+// the node rather than to what was learned about it.
+func (s *simultaneousStats) bare() *simultaneousStats {
+	if s == nil || s.picks == nil {
+		return nil
+	}
+	return &simultaneousStats{picks: append([]int(nil), s.picks...)}
+}
+
+// copyJoint returns an independent copy of every player's action statistics.
+func copyJoint(joint [][]ActionStats) [][]ActionStats {
+	if joint == nil {
+		return nil
+	}
+	cpy := make([][]ActionStats, len(joint))
+	for p := range joint {
+		cpy[p] = append([]ActionStats(nil), joint[p]...)
+	}
+	return cpy
+}
+
+// selectJoint chooses an action for every player acting at this node, and
+// returns the child for the joint action. If the child did not exist it is
+// created, and expanded is true.
+func (p SimultaneousPolicy) selectJoint(node *Node, ss SimultaneousState, explorationParam float64) (child *Node, expanded bool) {
+	stats := node.jointStats(ss)
+	picks := make([]int, len(stats))
+	choices := make([]Key, len(stats))
+	for player, s := range stats {
+		picks[player] = -1
+		if len(s) <= 0 {
+			continue
+		}
+		probabilities := p.bandit().Probabilities(s, node.Visits(), explorationParam)
+		picks[player] = sampleIndex(node.Rand(), probabilities)
+		choices[player] = s[picks[player]].Key
+	}
+	k := ss.JointAction(choices)
+	if child := node.GetChild(k); child != nil {
+		return child, false
+	}
+	action, ok := node.State.LegalActions()[k]
+	if !ok {
+		panic(fmt.Sprintf("%v", IllegalChildKey{key: k}))
+	}
+	n, err := NewNode(node.NumPlayers())
+	if err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+	n.State = action(node.State.Copy())
+	n.policy = n.State.Policy()
+	n.joint = &simultaneousStats{picks: picks}
+	return node.SetChild(k, &n), true
+}
+
+// bestJointAction returns the joint action made up of every player's most
+// chosen action at this simultaneous-move node.
+func (node *Node) bestJointAction(ss SimultaneousState) Key {
+	choices := make([]Key, len(node.joint.players))
+	for p, s := range node.joint.players {
+		best := -1
+		for i := range s {
+			if best < 0 || s[i].Visits > s[best].Visits {
+				best = i
+			}
+		}
+		if best >= 0 {
+			choices[p] = s[best].Key
+		}
+	}
+	return ss.JointAction(choices)
+}
+
+/******** IMPLEMENT Policy ********/
+
+// Select selects the child with the highest UCB, or the joint action of every
+// player's choice at simultaneous-move nodes, until a node that is not fully
+// expanded is found.
+func (p SimultaneousPolicy) Select(node *Node, explorationParam float64) *Node {
+	n := node
+	for n != nil && (n.IsRoot() || !n.IsTerminal()) {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if ss, ok := n.simultaneous(); ok {
+			child, expanded := p.selectJoint(n, ss, explorationParam)
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if !n.IsExhausted() {
+			return p.Expand(n, explorationParam)
+		}
+		if n.IsLeaf() {
+			break
+		}
+		_, n = n.selectBestChild(explorationParam)
+	}
+	return n
+}
+
+// Expand acts in exactly the same way as the UCTPolicy
+func (p SimultaneousPolicy) Expand(node *Node, explorationParam float64) *Node {
+	return UCTPolicy{}.Expand(node, explorationParam)
+}
+
+// Simulate acts in exactly the same way as the UCTPolicy
+func (p SimultaneousPolicy) Simulate(node *Node) float64 {
+	return UCTPolicy{Playout: p.Playout}.Simulate(node)
+}
+
+// SimulateScores simulates a playout, as the UCTPolicy does, returning
+// its score along with the final score of every player.
+func (p SimultaneousPolicy) SimulateScores(node *Node) (float64, []float64) {
+	state, _ := playout(node, p.Playout)
+	scores := make([]float64, node.NumPlayers())
+	for player := range scores {
+		scores[player] = state.Score(uint(player))
+	}
+	return state.Score(state.Player()), scores
+}
+
+// Backpropagate acts in exactly the same way as the UCTPolicy, without the final
+// score of every player no action statistics can be updated.
+func (p SimultaneousPolicy) Backpropagate(node *Node, score float64) {
+	UCTPolicy{}.Backpropagate(node, score)
+}
+
+// BackpropagateN acts in exactly the same way as the UCTPolicy, without the
+// final score of every player no action statistics can be updated.
+func (p SimultaneousPolicy) BackpropagateN(node *Node, score float64, visits int64) {
+	UCTPolicy{}.BackpropagateN(node, score, visits)
+}
+
+// BackpropagateScores acts in exactly the same way as the UCTPolicy, then
+// updates the statistics of the action each player chose at every
+// simultaneous-move node on the way to the root, with that player's score.
+func (p SimultaneousPolicy) BackpropagateScores(node *Node, score float64, scores []float64) {
+	p.backpropagatePath(parentPath(node), Result{Score: score, Scores: scores}, 1)
+}
+
+func (p SimultaneousPolicy) backpropagatePath(path path, result Result, visits int64) {
+	UCTPolicy{}.backpropagatePath(path, result, visits)
+	for _, e := range path {
+		if e.parent == nil || e.parent.joint == nil || e.node.joint == nil {
+			continue
+		}
+		for player, i := range e.node.joint.picks {
+			if i < 0 || player >= len(e.parent.joint.players) || player >= len(result.Scores) {
+				continue
+			}
+			stats := e.parent.joint.players[player]
+			p.bandit().Update(stats, i, result.Scores[player])
+			stats[i].Visits++
+			stats[i].Score += result.Scores[player]
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package montecarlo
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// matrixState is a one-shot game in which two players simultaneously choose a
+// row and a column of a payoff matrix, player 0 scoring the payoff and player
+// 1 scoring one minus the payoff
+type matrixState struct {
+	payoff [2][2]float64
+	row    int
+	col    int
+	policy Policy
+}
+
+var matrixRows, matrixCols = []Key{"top", "bottom"}, []Key{"left", "right"}
+
+func matrixActions() ActionSet {
+	actions := make(ActionSet)
+	for r := range matrixRows {
+		for c := range matrixCols {
+			row, col := r, c
+			actions[matrixRows[r].(string)+"/"+matrixCols[c].(string)] = func(state State) State {
+				s := state.(matrixState)
+				s.row, s.col = row, col
+				return s
+			}
+		}
+	}
+	return actions
+}
+
+func (s matrixState) LegalActions() ActionSet {
+	if s.row >= 0 {
+		return ActionSet{}
+	}
+	return matrixActions()
+}
+
+func (s matrixState) PlayerActions() [][]Key {
+	if s.row >= 0 {
+		return [][]Key{nil, nil}
+	}
+	return [][]Key{matrixRows, matrixCols}
+}
+
+func (s matrixState) JointAction(choices []Key) Key {
+	return choices[0].(string) + "/" + choices[1].(string)
+}
+
+func (s matrixState) Score(player uint) float64 {
+	if s.row < 0 {
+		return 0
+	}
+	if player == 0 {
+		return s.payoff[s.row][s.col]
+	}
+	return 1 - s.payoff[s.row][s.col]
+}
+
+func (s matrixState) Bias() float64  { return 0 }
+func (s matrixState) Copy() State    { return s }
+func (s matrixState) Player() uint   { return 0 }
+func (s matrixState) Policy() Policy { return s.policy }
+
+func TestSimultaneousDominantActions(t *testing.T) {
+	// top is always better for player 0, and right for player 1
+	payoff := [2][2]float64{{0.6, 0.4}, {0.5, 0.2}}
+	for _, bandit := range []Bandit{DecoupledUCB{}, EXP3{Gamma: 0.2}, RegretMatching{Gamma: 0.1}} {
+		state := matrixState{payoff: payoff, row: -1, col: -1, policy: NewSimultaneousPolicy(bandit)}
+		mcts, err := NewMultiplayerMCTS(2, state, matrixActions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mcts.SetRand(rand.New(rand.NewSource(1)))
+		result, err := mcts.Search(3000, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, Key("top/right"), result.BestKey, "%T", bandit)
+		if assert.Equal(t, 2, len(result.PlayerStats)) {
+			for _, stats := range result.PlayerStats {
+				visits := int64(0)
+				for _, s := range stats {
+					visits += s.Visits
+				}
+				// every iteration updates every player's choice
+				assert.Equal(t, int64(3000), visits)
+			}
+		}
+	}
+}
+
+func TestSimultaneousMatchingPennies(t *testing.T) {
+	// player 0 wins if the choices match, so both should mix evenly
+	payoff := [2][2]float64{{1, 0}, {0, 1}}
+	for _, bandit := range []Bandit{EXP3{Gamma: 0.2}, RegretMatching{Gamma: 0.1}} {
+		state := matrixState{payoff: payoff, row: -1, col: -1, policy: NewSimultaneousPolicy(bandit)}
+		mcts, err := NewMultiplayerMCTS(2, state, matrixActions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mcts.SetRand(rand.New(rand.NewSource(1)))
+		if _, err := mcts.Search(5000, 0.5); err != nil {
+			t.Fatal(err)
+		}
+		root := mcts.Tree().Root()
+		for player := uint(0); player < 2; player++ {
+			stats := root.PlayerStats(player)
+			first := float64(stats[0].Visits) / float64(stats[0].Visits+stats[1].Visits)
+			assert.InDelta(t, 0.5, first, 0.1, "%T", bandit)
+		}
+	}
+}
+
+func TestSimultaneousLeafParallel(t *testing.T) {
+	payoff := [2][2]float64{{0.6, 0.4}, {0.5, 0.2}}
+	state := matrixState{payoff: payoff, row: -1, col: -1, policy: NewSimultaneousPolicy(nil)}
+	mcts, err := NewMultiplayerMCTS(2, state, matrixActions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := mcts.LeafParallelSearch(4, 200, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	visits := int64(0)
+	for _, s := range result.PlayerStats[0] {
+		visits += s.Visits
+	}
+	assert.Equal(t, int64(4*200), visits)
+}
+
+// matrixCodec encodes a matrix state as its row and column, plus one, followed
+// by its payoffs in hundredths. Decoded states use a SimultaneousPolicy.
+type matrixCodec struct{}
+
+func (c matrixCodec) Encode(state State) ([]byte, error) {
+	s, ok := state.(matrixState)
+	if !ok {
+		return nil, errors.New("not a matrix state")
+	}
+	data := []byte{byte(s.row + 1), byte(s.col + 1)}
+	for _, row := range s.payoff {
+		for _, payoff := range row {
+			data = append(data, byte(payoff*100+0.5))
+		}
+	}
+	return data, nil
+}
+
+func (c matrixCodec) Decode(data []byte) (State, error) {
+	if len(data) != 6 {
+		return nil, errors.New("bad matrix state")
+	}
+	s := matrixState{row: int(data[0]) - 1, col: int(data[1]) - 1, policy: NewSimultaneousPolicy(nil)}
+	for i := range data[2:] {
+		s.payoff[i/2][i%2] = float64(data[2+i]) / 100
+	}
+	return s, nil
+}
+
+func TestSimultaneousNetworkRootParallel(t *testing.T) {
+	payoff := [2][2]float64{{0.6, 0.4}, {0.5, 0.2}}
+	state := matrixState{payoff: payoff, row: -1, col: -1, policy: NewSimultaneousPolicy(nil)}
+	mcts, err := NewMultiplayerMCTS(2, state, matrixActions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var workers []Worker
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		go ServeWorker(l, matrixCodec{})
+		w, err := DialWorker(l.Addr().String(), matrixCodec{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		workers = append(workers, w)
+	}
+	result, err := mcts.ParallelSearch(RootParallel{}, workers, 1000, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the players' statistics come back with the remote trees
+	assert.Equal(t, Key("top/right"), result.BestKey)
+	if assert.Equal(t, 2, len(result.PlayerStats)) {
+		for _, stats := range result.PlayerStats {
+			visits := int64(0)
+			for _, s := range stats {
+				visits += s.Visits
+			}
+			assert.Equal(t, int64(2*1000), visits)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package montecarlo
+
+import "math"
+
+// SolverPolicy is the UCTPolicy extended with MCTS-Solver (Winands et al. 2008:
+// Monte-Carlo Tree Search Solver - Computers and Games, LNCS vol. 5131).
+//
+// The winner of a terminal state is the player with the strictly highest score,
+// a terminal state with no such player (such as a draw) is never proven. Proofs
+// are propagated up the tree during backpropagation: a node is proven to be won
+// by its player if any of its children are, and proven to be won by another
+// player if every legal action leads to a child proven to be won by that same
+// player. Chance nodes are only proven if every outcome is won by the same
+// player. Solved children are skipped during selection, and the search stops
+// once the root is solved.
+type SolverPolicy struct {
+	// Playout chooses the actions taken during simulation, see UCTPolicy.
+	Playout PlayoutPolicy
+}
+
+// solverProof is the proof of a node's winner, see SolverPolicy.
+type solverProof struct {
+	winner uint
+}
+
+// ProvenWinner returns the player proven to win from the state of this node,
+// whatever the players choose to do. The boolean is false if the node has not
+// been proven.
+func (node Node) ProvenWinner() (uint, bool) {
+	if node.proof == nil {
+		return 0, false
+	}
+	return node.proof.winner, true
+}
+
+// proven returns true once the winner of this node's state is known, whatever
+// the players choose to do from it.
+func (node Node) proven() bool {
+	return node.proof != nil
+}
+
+// setWinner marks this node as proven to be won by the passed player.
+func (node *Node) setWinner(player uint) {
+	node.proof = &solverProof{winner: player}
+}
+
+// copy returns an independent copy of the proof.
+func (p *solverProof) copy() *solverProof {
+	if p == nil {
+		return nil
+	}
+	cpy := *p
+	return &cpy
+}
+
+// merge returns this proof, or a copy of other if this node is not proven.
+func (p *solverProof) merge(other *solverProof) *solverProof {
+	if p == nil {
+		return other.copy()
+	}
+	return p
+}
+
+// terminalWinner returns the player with the strictly highest score in the
+// passed terminal state, the boolean is false if there is no such player.
+func terminalWinner(state State, numPlayers uint) (uint, bool) {
+	winner, unique := uint(0), false
+	best := math.Inf(-1)
+	for p := uint(0); p < numPlayers; p++ {
+		score := state.Score(p)
+		if score > best {
+			best, winner, unique = score, p, true
+		} else if score == best {
+			unique = false
+		}
+	}
+	return winner, unique
+}
+
+// prove tries to prove the winner of this node, from its state if it is
+// terminal or from its children otherwise. Returns true if the node is proven.
+func (node *Node) prove() bool {
+	if node.proven() {
+		return true
+	}
+	if node.IsTerminal() {
+		if node.State == nil {
+			return false
+		}
+		if winner, ok := terminalWinner(node.State, node.NumPlayers()); ok {
+			node.setWinner(winner)
+		}
+		return node.proven()
+	}
+	player := node.Player()
+	// the player cannot choose the outcome of a chance node
+	chance := node.IsChance()
+	// only a fully expanded node can be lost by its player
+	same := node.IsExhausted()
+	var winner uint
+	first := true
+	for _, k := range sortedChildKeys(node.children) {
+		child := node.children[k]
+		if !child.proven() {
+			same = false
+			continue
+		}
+		if child.proof.winner == player && !chance {
+			node.setWinner(player)
+			return true
+		}
+		if first {
+			winner, first = child.proof.winner, false
+		} else if child.proof.winner != winner {
+			same = false
+		}
+	}
+	if same && !first {
+		node.setWinner(winner)
+	}
+	return node.proven()
+}
+
+// selectFinalChild selects the child of this node to play: a child proven to be
+// won by this node's player, or proven by its bounds to give this node's exact
+// score, if there is one; otherwise the child with the best mean score.
+// Children proven to be won by another player, or whose bounds show they are
+// worse than another child, are avoided whenever possible. Ties are broken by
+// key order, so that reporting on a search doesn't change its course.
+func (node *Node) selectFinalChild() (Key, *Node) {
+	player := node.Player()
+	exact := node.bounds != nil && closeEnough(node.bounds.pess[player], node.bounds.opt[player])
+	for _, k := range sortedChildKeys(node.children) {
+		child := node.children[k]
+		if child.proven() && child.proof.winner == player {
+			return k, child
+		}
+		if exact && child.bounds != nil && closeEnough(child.bounds.pess[player], node.bounds.pess[player]) {
+			return k, child
+		}
+	}
+	return node.firstChildBy(func(child *Node) float64 {
+		if child.proven() || (node.bounds != nil && child.bounds != nil && child.bounds.opt[player] < node.bounds.pess[player]) {
+			return math.Inf(-1)
+		}
+		return node.childValue(child, 0)
+	})
+}
+
+/******** IMPLEMENT Policy ********/
+
+// Select selects the unsolved child with the highest UCB, until a node that is
+// not fully expanded, or a solved node, is found. Solved children are only
+// selected when every child is solved.
+func (p SolverPolicy) Select(node *Node, explorationParam float64) *Node {
+	n := node
+	for n != nil && (n.IsRoot() || !n.IsTerminal()) && !n.proven() {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
+		if !n.IsExhausted() {
+			return p.Expand(n, explorationParam)
+		}
+		if n.IsLeaf() {
+			break
+		}
+		parent := n
+		_, n = parent.selectChildBy(func(child *Node) float64 {
+			if child.proven() {
+				return math.Inf(-1)
+			}
+			return parent.childValue(child, explorationParam)
+		})
+	}
+	return n
+}
+
+// Expand acts in exactly the same way as the UCTPolicy
+func (p SolverPolicy) Expand(node *Node, explorationParam float64) *Node {
+	return UCTPolicy{}.Expand(node, explorationParam)
+}
+
+// Simulate acts in exactly the same way as the UCTPolicy
+func (p SolverPolicy) Simulate(node *Node) float64 {
+	return UCTPolicy{Playout: p.Playout}.Simulate(node)
+}
+
+// Backpropagate acts in exactly the same way as the UCTPolicy, then propagates
+// any proofs up the tree.
+func (p SolverPolicy) Backpropagate(node *Node, score float64) {
+	p.BackpropagateN(node, score, 1)
+}
+
+// BackpropagateN acts in exactly the same way as the UCTPolicy, then
+// propagates any proofs up the tree.
+func (p SolverPolicy) BackpropagateN(node *Node, score float64, visits int64) {
+	p.backpropagatePath(parentPath(node), Result{Score: score}, visits)
+}
+
+func (p SolverPolicy) backpropagatePath(path path, result Result, visits int64) {
+	UCTPolicy{}.backpropagatePath(path, result, visits)
+	for _, e := range path {
+		if !e.node.prove() {
+			break
+		}
+	}
+}
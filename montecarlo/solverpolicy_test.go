@@ -0,0 +1,95 @@
+package montecarlo_test
+
+import (
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// boardState builds a game state from rows of 'X', 'O' and '.' characters, with
+// X to move if xTurn is true
+func boardState(xTurn bool, rows ...string) gameState {
+	state := initState()
+	state.turn = xTurn
+	for i, row := range rows {
+		for j, c := range row {
+			switch c {
+			case 'X':
+				state.board[i][j] = playerX
+			case 'O':
+				state.board[i][j] = playerO
+			}
+		}
+	}
+	return state
+}
+
+func TestSolverFindsWin(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.SolverPolicy{}
+	defer func() { statePolicy = nil }()
+	state := boardState(true,
+		"XX.",
+		"OO.",
+		"...",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(1000, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, montecarlo.Key("SET_X_0_2"), result.BestKey)
+	winner, proven := ai.Tree().Root().ProvenWinner()
+	assert.True(t, proven)
+	assert.Equal(t, uint(0), winner)
+	// the search stops once the root is solved
+	assert.True(t, result.Iterations < 1000)
+}
+
+func TestSolverProvesLoss(t *testing.T) {
+	makeActions()
+	statePolicy = montecarlo.SolverPolicy{}
+	defer func() { statePolicy = nil }()
+	// X threatens both the top row and the left column
+	state := boardState(false,
+		"XX.",
+		"XO.",
+		"..O",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ai.Search(1000, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	root := ai.Tree().Root()
+	winner, proven := root.ProvenWinner()
+	assert.True(t, proven)
+	assert.Equal(t, uint(0), winner)
+	for k := range state.LegalActions() {
+		winner, proven := root.GetChild(k).ProvenWinner()
+		assert.True(t, proven, "every move for O should be proven lost")
+		assert.Equal(t, uint(0), winner)
+	}
+}
+
+func TestSearchTerminalRoot(t *testing.T) {
+	makeActions()
+	state := boardState(false,
+		"XXX",
+		"OO.",
+		"...",
+	)
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ai.Search(10, 1.5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), result.Iterations)
+}
@@ -0,0 +1,144 @@
+package montecarlo
+
+import "sync"
+
+// Strategy describes how a search is split up into jobs for a pool of workers.
+type Strategy interface {
+	// Search searches the passed tree, using the scheduler's workers, until
+	// level is reached. What level means is up to each strategy.
+	Search(tree *Tree, scheduler *Scheduler, level int64, expl float64) error
+}
+
+// RootParallel is a root-parallel Strategy. Each worker searches its own
+// independent tree, grown from a copy of the root state, for level iterations.
+// Once every worker has finished, their trees are merged into the searched tree
+// in order of worker index. Each worker's tree is given a random source seeded
+// from the searched tree's, and shares nodes between transpositions if the
+// searched tree does.
+type RootParallel struct{}
+
+// Search implements Strategy.
+func (rp RootParallel) Search(tree *Tree, scheduler *Scheduler, level int64, expl float64) error {
+	root := tree.Root()
+	// create a separate tree for each worker up front, so that no two workers
+	// ever share a node
+	jobs := make([]Job, scheduler.NumWorkers())
+	for i := range jobs {
+		t, err := NewTree(root.NumPlayers(), root.State.Copy(), tree.PossibleActions())
+		if err != nil {
+			return err
+		}
+		t.SetRand(newChildRand(root.Rand()))
+		if root.table() != nil {
+			t.EnableTranspositions()
+		}
+		jobs[i] = Job{
+			Kind:             SubtreeJob,
+			Tree:             &t,
+			Iterations:       level,
+			ExplorationParam: expl,
+		}
+	}
+	// merge all searched trees, always in the same order
+	for _, result := range scheduler.DoEach(jobs...) {
+		if result.Err != nil {
+			return result.Err
+		}
+		if err := tree.Merge(*result.Tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LeafParallel is a leaf-parallel Strategy. Selection, expansion and
+// backpropagation happen on a single goroutine. For each selected node, every
+// worker runs one simulation, and their total score is propagated with one
+// visit per simulation. level is the number of nodes selected.
+type LeafParallel struct{}
+
+// Search implements Strategy.
+func (lp LeafParallel) Search(tree *Tree, scheduler *Scheduler, level int64, expl float64) error {
+	root := tree.Root()
+	jobs := make([]Job, scheduler.NumWorkers())
+	for i := int64(0); i < level; i++ {
+		path := selectPath(root, expl)
+		node := path.node()
+		// each simulation is given its own detached copy of the node
+		for j := range jobs {
+			jobs[j] = Job{Kind: SimulationJob, Node: path.detach(newChildRand(node.Rand()))}
+		}
+		results := scheduler.Do(jobs...)
+		score := float64(0)
+		for _, result := range results {
+			if result.Err != nil {
+				return result.Err
+			}
+			score += result.Score
+		}
+		if separateResults(node) {
+			// the actions or scores of each simulation must be propagated
+			// separately
+			for _, result := range results {
+				backpropagate(path, result)
+			}
+			continue
+		}
+		backpropagateN(path, score, int64(len(jobs)))
+	}
+	return nil
+}
+
+// TreeParallel is a tree-parallel Strategy. One goroutine per worker runs
+// level iterations over the shared tree, with each simulation carried out by
+// the pool.
+//
+// Selection, expansion and backpropagation are guarded by a single lock on the
+// tree, whereas simulations run concurrently (Chaslot et al. 2008: Parallel
+// Monte-Carlo Tree Search). Whilst a simulation is in progress, every node on
+// the path to its node carries VirtualLoss extra visits with no score,
+// discouraging other goroutines from selecting the same path. A VirtualLoss of
+// zero disables this.
+type TreeParallel struct {
+	VirtualLoss int64
+}
+
+// Search implements Strategy.
+func (tp TreeParallel) Search(tree *Tree, scheduler *Scheduler, level int64, expl float64) error {
+	root := tree.Root()
+	var lock sync.Mutex
+	errs := make([]error, scheduler.NumWorkers())
+	var counter sync.WaitGroup
+	counter.Add(len(errs))
+	for threadno := range errs {
+		go func(threadno int) {
+			defer counter.Done()
+			for i := int64(0); i < level; i++ {
+				lock.Lock()
+				path := selectPath(root, expl)
+				path.addVirtualLoss(tp.VirtualLoss)
+				leaf := path.detach(newChildRand(path.node().Rand()))
+				lock.Unlock()
+				// the simulation never touches the shared tree
+				result := scheduler.Do(Job{Kind: SimulationJob, Node: leaf})[0]
+				lock.Lock()
+				path.addVirtualLoss(-tp.VirtualLoss)
+				if result.Err == nil {
+					backpropagate(path, result)
+				}
+				lock.Unlock()
+				if result.Err != nil {
+					errs[threadno] = result.Err
+					return
+				}
+			}
+		}(threadno)
+	}
+	counter.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
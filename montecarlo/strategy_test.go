@@ -0,0 +1,147 @@
+package montecarlo
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// itemState is a two player game of taking turns to pick one of five items
+// until four have been picked, the player holding the first item wins.
+// Picking the same items in another order reaches the same state. The policy
+// is a UCTPolicy with slow random playouts unless one is given.
+type itemState struct {
+	held   [2]uint
+	policy Policy
+}
+
+func (ps itemState) LegalActions() ActionSet {
+	actions := make(ActionSet)
+	if ps.picked() >= 4 {
+		return actions
+	}
+	for i := uint(0); i < 5; i++ {
+		item := uint(1) << i
+		if (ps.held[0]|ps.held[1])&item != 0 {
+			continue
+		}
+		actions[int(i)] = func(state State) State {
+			next := state.(itemState)
+			next.held[next.Player()] |= item
+			return next
+		}
+	}
+	return actions
+}
+
+func (ps itemState) picked() int {
+	count := 0
+	for _, held := range ps.held {
+		for ; held != 0; held &= held - 1 {
+			count++
+		}
+	}
+	return count
+}
+
+func (ps itemState) Score(player uint) float64 {
+	if ps.held[player]&1 != 0 {
+		return 1
+	}
+	return 0
+}
+
+func (ps itemState) Bias() float64 { return 0 }
+func (ps itemState) Copy() State   { return ps }
+func (ps itemState) Player() uint  { return uint(ps.picked() % 2) }
+func (ps itemState) StateKey() Key { return ps.held }
+
+func (ps itemState) Policy() Policy {
+	if ps.policy == nil {
+		return UCTPolicy{Playout: slowPlayout{}}
+	}
+	return ps.policy
+}
+
+// slowPlayout chooses at random, slowly enough that simulations overlap
+type slowPlayout struct{}
+
+func (pp slowPlayout) Choose(state State, legalActions ActionSet, r *rand.Rand) Key {
+	time.Sleep(100 * time.Microsecond)
+	return UniformPlayout{}.Choose(state, legalActions, r)
+}
+
+func TestTreeParallelTranspositions(t *testing.T) {
+	tree, err := NewTree(2, itemState{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.EnableTranspositions()
+	tree.SetRand(rand.New(rand.NewSource(1)))
+	scheduler := NewScheduler(LocalWorkers(4))
+	err = TreeParallel{VirtualLoss: 3}.Search(&tree, scheduler, 200, 1)
+	scheduler.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.Root()
+	assert.Equal(t, int64(4*200), root.Visits())
+	// every node other than the root is only visited through the edges
+	// leading to it
+	incoming := make(map[*Node]int64)
+	seen := map[*Node]bool{root: true}
+	stack := []*Node{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		assert.True(t, n.Visits() >= 0, "negative visits")
+		for _, k := range sortedChildKeys(n.children) {
+			child := n.children[k]
+			incoming[child] += n.edgeVisits(child)
+			if !seen[child] {
+				seen[child] = true
+				stack = append(stack, child)
+			}
+		}
+	}
+	shared := false
+	for n, visits := range incoming {
+		assert.Equal(t, n.Visits(), visits)
+		if n.Visits() > n.Parent().edgeVisits(n) {
+			shared = true
+		}
+	}
+	assert.True(t, shared, "no node was reached by more than one path")
+}
+
+func TestTreeParallelTranspositionsNST(t *testing.T) {
+	// playouts read the actions leading to their node whilst other goroutines
+	// select through the same shared nodes, run with -race
+	tree, err := NewTree(2, itemState{policy: UCTPolicy{Playout: NewNST(2, 1, 0.5)}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.EnableTranspositions()
+	tree.SetRand(rand.New(rand.NewSource(1)))
+	scheduler := NewScheduler(LocalWorkers(4))
+	err = TreeParallel{VirtualLoss: 1}.Search(&tree, scheduler, 200, 1)
+	scheduler.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.Root()
+	assert.Equal(t, int64(4*200), root.Visits())
+	// every iteration takes at least one action for each of the players
+	stats := root.playoutStats()
+	for player := uint(0); player < 2; player++ {
+		visits := int64(0)
+		for item := 0; item < 5; item++ {
+			if g := stats.grams.next[PlayedAction{player, item}]; g != nil {
+				visits += g.visits
+			}
+		}
+		assert.True(t, visits >= 4*200)
+	}
+}
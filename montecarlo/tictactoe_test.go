@@ -41,6 +41,12 @@ func (state gameState) String() string {
 	return s
 }
 
+// StateKey implements montecarlo.TranspositionState, the board along with
+// whose turn it is.
+func (state gameState) StateKey() montecarlo.Key {
+	return fmt.Sprintf("%v%v", state.turn, state)
+}
+
 // isEnd returns true if there is a winner, along with either constant "playerOne",
 // or "playerTwo". If nobody won, then false is returned with the constant "empty".
 func (state gameState) isEnd() (bool, cell) {
@@ -237,7 +243,14 @@ func (state gameState) Player() uint {
 	return 1
 }
 
+// statePolicy is the policy given by every gameState, the UCTPolicy is given if
+// it is nil.
+var statePolicy montecarlo.Policy
+
 func (state gameState) Policy() montecarlo.Policy {
+	if statePolicy != nil {
+		return statePolicy
+	}
 	return montecarlo.UCTPolicy{}
 }
 
@@ -281,7 +294,11 @@ func TestExample(t *testing.T) {
 	if flip == 0 {
 		state.turn = !state.turn
 	}
-	//play the game
+	//play the game, reusing the same search tree for every move
+	ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+	if err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
 	var end bool
 	var winner cell
 	for end, winner = state.isEnd(); !end; end, winner = state.isEnd() {
@@ -292,28 +309,26 @@ func TestExample(t *testing.T) {
 				state.turn = !state.turn
 			*/
 			fmt.Println("X's turn...")
-			ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+			result, err := ai.Search(1000, float64(1)/math.Sqrt2)
 			if err != nil {
 				panic(fmt.Sprintf("%v", err))
 			}
-			index, action, err := ai.Search(1000, float64(1)/math.Sqrt2)
-			if err != nil {
+			fmt.Printf("X: \"taking action: %v\"\n", result.BestKey)
+			state = ((*result.BestAction)(state)).(gameState)
+			if err := ai.Advance(result.BestKey); err != nil {
 				panic(fmt.Sprintf("%v", err))
 			}
-			fmt.Printf("X: \"taking action: %v\"\n", index)
-			state = ((*action)(state)).(gameState)
 		} else {
 			fmt.Println("O's turn...")
-			ai, err := montecarlo.NewMultiplayerMCTS(2, state, actions)
+			result, err := ai.Search(1000, float64(1)/math.Sqrt2)
 			if err != nil {
 				panic(fmt.Sprintf("%v", err))
 			}
-			index, action, err := ai.Search(1000, float64(1)/math.Sqrt2)
-			if err != nil {
+			fmt.Printf("O: \"taking action: %v\"\n", result.BestKey)
+			state = ((*result.BestAction)(state)).(gameState)
+			if err := ai.Advance(result.BestKey); err != nil {
 				panic(fmt.Sprintf("%v", err))
 			}
-			fmt.Printf("O: \"taking action: %v\"\n", index)
-			state = ((*action)(state)).(gameState)
 		}
 		fmt.Printf("%v", state)
 	}
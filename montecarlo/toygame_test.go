@@ -0,0 +1,240 @@
+package montecarlo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// toyGame is a small game for tests, whose rules are given by the functions
+// below, each passed the keys of the moves played so far. Small games are
+// easiest written as tables of positions, see movesTable and scoresTable.
+type toyGame struct {
+	// keys are the keys of every move in the game
+	keys []Key
+	// moves returns the keys of the legal moves, none once the game is over
+	moves func(moves []Key) []Key
+	// score returns the passed player's score
+	score func(moves []Key, player uint) float64
+	// player returns the player to move, player 0 moves throughout if nil
+	player func(moves []Key) uint
+	// outcomes returns the probability of each legal move at chance positions
+	// and nil elsewhere, if it isn't nil (see ChanceState)
+	outcomes func(moves []Key) map[Key]float64
+	// evaluate returns a static evaluation of the position, states only
+	// implement Evaluator if it isn't nil
+	evaluate func(moves []Key) []float64
+	// policy returns the policy to search with, a UCTPolicy where it is or
+	// returns nil
+	policy func(moves []Key) Policy
+}
+
+// position names the position reached by the passed moves, as the keys of the
+// moves joined by "/", the start being ""
+func position(moves []Key) string {
+	names := make([]string, len(moves))
+	for i, k := range moves {
+		names[i] = fmt.Sprint(k)
+	}
+	return strings.Join(names, "/")
+}
+
+// movesTable gives the moves listed for each position, see position
+func movesTable(table map[string][]Key) func(moves []Key) []Key {
+	return func(moves []Key) []Key {
+		return table[position(moves)]
+	}
+}
+
+// scoresTable gives the score of every player listed for each position, and
+// zero for positions which aren't listed
+func scoresTable(table map[string][]float64) func(moves []Key, player uint) float64 {
+	return func(moves []Key, player uint) float64 {
+		if scores := table[position(moves)]; int(player) < len(scores) {
+			return scores[player]
+		}
+		return 0
+	}
+}
+
+// state returns the state reached by playing the passed moves from the start
+func (g *toyGame) state(moves ...Key) State {
+	s := toyState{g, moves}
+	if g.evaluate != nil {
+		return evaluatedToyState{s}
+	}
+	return s
+}
+
+// actions returns the action of every key of the game
+func (g *toyGame) actions() ActionSet {
+	actions := make(ActionSet, len(g.keys))
+	for _, k := range g.keys {
+		key := k
+		actions[key] = func(state State) State {
+			s := toyPosition(state)
+			return g.state(append(append([]Key(nil), s.moves...), key)...)
+		}
+	}
+	return actions
+}
+
+// toyState is a position of a toyGame
+type toyState struct {
+	game  *toyGame
+	moves []Key
+}
+
+// evaluatedToyState is a position of a toyGame with a static evaluation
+type evaluatedToyState struct {
+	toyState
+}
+
+// toyPosition returns the position of the passed toyGame state
+func toyPosition(state State) toyState {
+	if s, ok := state.(evaluatedToyState); ok {
+		return s.toyState
+	}
+	return state.(toyState)
+}
+
+func (s toyState) LegalActions() ActionSet {
+	all := s.game.actions()
+	legal := make(ActionSet)
+	for _, k := range s.game.moves(s.moves) {
+		legal[k] = all[k]
+	}
+	return legal
+}
+
+func (s toyState) Score(player uint) float64 {
+	return s.game.score(s.moves, player)
+}
+
+func (s toyState) Player() uint {
+	if s.game.player == nil {
+		return 0
+	}
+	return s.game.player(s.moves)
+}
+
+func (s toyState) Outcomes() map[Key]float64 {
+	if s.game.outcomes == nil {
+		return nil
+	}
+	return s.game.outcomes(s.moves)
+}
+
+func (s toyState) Policy() Policy {
+	if s.game.policy != nil {
+		if policy := s.game.policy(s.moves); policy != nil {
+			return policy
+		}
+	}
+	return UCTPolicy{}
+}
+
+func (s toyState) Bias() float64 { return 0 }
+func (s toyState) Copy() State   { return s.game.state(s.moves...) }
+
+func (s evaluatedToyState) Evaluate() []float64 {
+	return s.game.evaluate(s.moves)
+}
+
+var gambleOutcomes = map[Key]float64{"win": 0.3, "lose": 0.7}
+
+// gambleGame is a single player game: from the start the player either takes
+// a safe score of 0.6, or gambles on a coin which wins (a score of 1) with a
+// probability of 0.3 and loses (a score of 0) otherwise. The coin is a chance
+// node through its policy rather than through ChanceState if viaPolicy is set.
+func gambleGame(viaPolicy bool) *toyGame {
+	return &toyGame{
+		keys: []Key{"safe", "gamble", "win", "lose"},
+		moves: movesTable(map[string][]Key{
+			"":       {"safe", "gamble"},
+			"gamble": {"win", "lose"},
+		}),
+		score: scoresTable(map[string][]float64{"safe": {0.6}, "gamble/win": {1}}),
+		outcomes: func(moves []Key) map[Key]float64 {
+			if position(moves) == "gamble" && !viaPolicy {
+				return gambleOutcomes
+			}
+			return nil
+		},
+		policy: func(moves []Key) Policy {
+			if position(moves) == "gamble" && viaPolicy {
+				return NewDeterminizationPolicy(gambleOutcomes)
+			}
+			return nil
+		},
+	}
+}
+
+// pairGame is a single player game of two moves, each "a" or "b", scoring 1 if
+// both moves are "a"
+func pairGame(policy Policy) *toyGame {
+	return &toyGame{
+		keys: []Key{"a", "b"},
+		moves: movesTable(map[string][]Key{
+			"":  {"a", "b"},
+			"a": {"a", "b"},
+			"b": {"a", "b"},
+		}),
+		score:  scoresTable(map[string][]float64{"a/a": {1}}),
+		policy: func(moves []Key) Policy { return policy },
+	}
+}
+
+// guessGame is a two player game: player 0 picks "a" or "b", then player 1
+// guesses the pick with "guess_a" or "guess_b". Player 1 scores 1 for a right
+// guess, and player 0 scores 1 otherwise.
+func guessGame(policy Policy) *toyGame {
+	guesses := []Key{"guess_a", "guess_b"}
+	return &toyGame{
+		keys:  []Key{"a", "b", "guess_a", "guess_b"},
+		moves: movesTable(map[string][]Key{"": {"a", "b"}, "a": guesses, "b": guesses}),
+		score: scoresTable(map[string][]float64{
+			"a/guess_a": {0, 1},
+			"a/guess_b": {1, 0},
+			"b/guess_a": {1, 0},
+			"b/guess_b": {0, 1},
+		}),
+		player: func(moves []Key) uint { return uint(len(moves) % 2) },
+		policy: func(moves []Key) Policy { return policy },
+	}
+}
+
+// walkGame is a long single player game: each of length moves steps up or
+// down, and the player scores 1 if they finish above where they started. The
+// evaluation is how far up they are, as a fraction of the length of the game.
+func walkGame(length int, policy Policy) *toyGame {
+	height := func(moves []Key) int {
+		h := 0
+		for _, k := range moves {
+			if k == "up" {
+				h++
+			} else {
+				h--
+			}
+		}
+		return h
+	}
+	return &toyGame{
+		keys: []Key{"up", "down"},
+		moves: func(moves []Key) []Key {
+			if len(moves) >= length {
+				return nil
+			}
+			return []Key{"up", "down"}
+		},
+		score: func(moves []Key, player uint) float64 {
+			if height(moves) > 0 {
+				return 1
+			}
+			return 0
+		},
+		evaluate: func(moves []Key) []float64 {
+			return []float64{0.5 + float64(height(moves))/float64(2*length)}
+		},
+		policy: func(moves []Key) Policy { return policy },
+	}
+}
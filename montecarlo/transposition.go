@@ -0,0 +1,287 @@
+package montecarlo
+
+import "math"
+
+// TranspositionState is a State which can tell whether it is the same as
+// another state, however either was reached (see Tree.EnableTranspositions).
+type TranspositionState interface {
+	State
+	// StateKey returns a key which is the same for equal states and differs
+	// otherwise, such as a hash of the board along with the player to move.
+	// Games in which a state can repeat should include whatever tells the
+	// repetitions apart, such as the number of moves played, in the key.
+	StateKey() Key
+}
+
+// transpositions is a transposition table: it maps the key of each state in a
+// tree to the node shared by every path reaching that state.
+type transpositions struct {
+	nodes map[Key]*Node
+	// selected holds the edge through which each node was reached by the
+	// selection in progress, it is nil while there is none
+	selected map[*Node]edge
+}
+
+// sharing is what a node of a tree with transpositions keeps: the table of the
+// tree, and the visits of the edge to each child.
+type sharing struct {
+	table *transpositions
+	edges map[*Node]int64
+}
+
+// table returns the transposition table of this node's tree, nil unless nodes
+// are shared between paths reaching the same state.
+func (node *Node) table() *transpositions {
+	if node.shared == nil {
+		return nil
+	}
+	return node.shared.table
+}
+
+// copy returns the edges of a copy of a node, to the passed copies of its
+// children, keyed by the original child. The copy has no table until it is
+// registered in one.
+func (s *sharing) copy(copies map[*Node]*Node) *sharing {
+	if s == nil {
+		return nil
+	}
+	cpy := &sharing{}
+	for child, visits := range s.edges {
+		if c, ok := copies[child]; ok {
+			if cpy.edges == nil {
+				cpy.edges = make(map[*Node]int64)
+			}
+			cpy.edges[c] = visits
+		}
+	}
+	return cpy
+}
+
+func newTranspositions() *transpositions {
+	return &transpositions{
+		nodes: make(map[Key]*Node),
+	}
+}
+
+// stateKey returns the key of the passed state, if it is a TranspositionState.
+func stateKey(state State) (Key, bool) {
+	ts, ok := state.(TranspositionState)
+	if !ok {
+		return nil, false
+	}
+	return ts.StateKey(), true
+}
+
+// lookup returns the node already in the table for the state of the passed
+// child of parent, or nil if there is none. A node on the path leading to the
+// parent is never returned, since linking it would create a cycle.
+func (t *transpositions) lookup(parent, child *Node) *Node {
+	if t == nil {
+		return nil
+	}
+	key, ok := stateKey(child.State)
+	if !ok {
+		return nil
+	}
+	existing, ok := t.nodes[key]
+	if !ok || existing == child {
+		return nil
+	}
+	for n := parent; n != nil; n = t.parentOf(n) {
+		if n == existing {
+			return nil
+		}
+	}
+	return existing
+}
+
+// register adds the passed node and all of its descendants to the table,
+// unless a node with the same state is already in it. Returns the number of
+// distinct nodes that were reached.
+func (t *transpositions) register(node *Node) int {
+	if t == nil {
+		return 0
+	}
+	count := 0
+	stack := []*Node{node}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n == nil || n.table() == t {
+			continue
+		}
+		if n.shared == nil {
+			n.shared = &sharing{}
+		}
+		n.shared.table = t
+		count++
+		if key, ok := stateKey(n.State); ok {
+			if _, found := t.nodes[key]; !found {
+				t.nodes[key] = n
+			}
+		}
+		for _, k := range sortedChildKeys(n.children) {
+			stack = append(stack, n.children[k])
+		}
+	}
+	return count
+}
+
+// adopt links each node below the passed root whose parent is not in the
+// table, such as one discarded by Tree.Advance, to the first parent in the
+// table it is reached from.
+func (t *transpositions) adopt(root *Node) {
+	queue := []*Node{root}
+	seen := map[*Node]bool{root: true}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, k := range sortedChildKeys(n.children) {
+			child := n.children[k]
+			if child == nil || seen[child] {
+				continue
+			}
+			seen[child] = true
+			if child.parent == nil || child.parent.table() != t {
+				child.parent, child.key = n, k
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// reached records that the passed child of parent, with the passed key, was
+// reached by the selection in progress, if there is one.
+func (t *transpositions) reached(parent *Node, key Key, child *Node) {
+	if t == nil || t.selected == nil {
+		return
+	}
+	t.selected[child] = edge{parent, child, key}
+}
+
+// edgeTo returns the edge through which the passed node was reached by the
+// selection in progress, or the edge from its own parent otherwise.
+func (t *transpositions) edgeTo(node *Node) edge {
+	if t != nil {
+		if e, ok := t.selected[node]; ok {
+			return e
+		}
+	}
+	return edge{node.parent, node, node.key}
+}
+
+// parentOf returns the parent through which the passed node was reached by the
+// selection in progress, or its own parent otherwise.
+func (t *transpositions) parentOf(node *Node) *Node {
+	return t.edgeTo(node).parent
+}
+
+// EnableTranspositions shares nodes between every path of the tree reaching
+// the same state, turning the tree into a directed acyclic graph. States must
+// implement TranspositionState, others are never shared. Nodes already in the
+// tree are added to the transposition table as they are.
+//
+// The parent of a shared node is the one it was first created below. Selection
+// records the path it takes through shared nodes instead, and the result of
+// each iteration is propagated along that path, as are the actions given to
+// adaptive playout policies (see MAST). Policies from outside this package
+// propagate along the parents of the simulated node. The visits of each edge
+// between a node and its children are kept apart from the visits of the
+// children themselves, and selection uses them as in UCT3 (Childs et al. 2008:
+// Transpositions and Move Groups in Monte Carlo Tree Search - IEEE Symposium
+// on Computational Intelligence and Games). Merging nodes of a tree with
+// transpositions matches them by their state key rather than by their path.
+//
+// The size of each node other than the root only counts the nodes first
+// created below it.
+func (tree *Tree) EnableTranspositions() {
+	root := tree.Root()
+	if root.table() != nil {
+		return
+	}
+	root.size = newTranspositions().register(root)
+}
+
+// edgeVisits returns the number of visits of the edge between this node and
+// the passed child, which is the child's own number of visits unless it is
+// shared between several paths.
+func (node *Node) edgeVisits(child *Node) int64 {
+	if node.shared == nil || node.shared.edges == nil {
+		return child.Visits()
+	}
+	return node.shared.edges[child]
+}
+
+// addEdgeVisits adds to the visits of the edge between this node and the
+// passed child, if this node belongs to a tree with transpositions.
+func (node *Node) addEdgeVisits(child *Node, visits int64) {
+	if node.table() == nil {
+		return
+	}
+	if node.shared.edges == nil {
+		node.shared.edges = make(map[*Node]int64)
+	}
+	node.shared.edges[child] += visits
+}
+
+// edgeConfidenceBound is the UCB of the passed child of this node as in UCT3:
+// the mean score of the child is shared by every path reaching it, while the
+// exploration term uses the visits of the edge from this node.
+func (node *Node) edgeConfidenceBound(child *Node, explorationParam float64) float64 {
+	expl := math.Max(explorationParam, 0)
+	visits := float64(node.edgeVisits(child))
+	if visits <= 0 || child.Visits() <= 0 {
+		return math.Inf(1)
+	}
+	score := child.expectedScore(node.Player())
+	return score + expl*math.Sqrt(float64(2)*math.Log(float64(node.Visits()))/visits)
+}
+
+// bare returns a parentless, childless copy of this node without any of its
+// statistics.
+func (node Node) bare() *Node {
+	// will not throw any error since we're already using a valid player count
+	n, _ := NewNode(node.NumPlayers())
+	if node.State != nil {
+		n.State = node.State.Copy()
+	}
+	n.policy = node.policy
+	n.puct = node.puct.bare()
+	n.joint = node.joint.bare()
+	return &n
+}
+
+// mergeTranspositions merges the descendants of other into this node's tree,
+// matching each of them with the node for the same state rather than by the
+// path leading to it. Nodes reached by several paths in other are merged once.
+func (node *Node) mergeTranspositions(other *Node) error {
+	type pair struct {
+		ours, theirs *Node
+	}
+	merged := map[*Node]bool{other: true}
+	queue := []pair{{node, other}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, k := range sortedChildKeys(p.theirs.children) {
+			theirs := p.theirs.children[k]
+			if theirs == nil {
+				continue
+			}
+			ours := p.ours.GetChild(k)
+			if ours == nil {
+				ours = p.ours.SetChild(k, theirs.bare())
+			}
+			p.ours.addEdgeVisits(ours, p.theirs.edgeVisits(theirs))
+			if merged[theirs] {
+				continue
+			}
+			merged[theirs] = true
+			if err := ours.mergeStats(*theirs); err != nil {
+				return err
+			}
+			queue = append(queue, pair{ours, theirs})
+		}
+	}
+	return nil
+}
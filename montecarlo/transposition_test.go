@@ -0,0 +1,156 @@
+package montecarlo_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/samwalls/fullmonte/montecarlo"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// addPath takes the actions with the passed keys from the root of the tree,
+// creating a node for each that has no child yet, and returns the last node
+func addPath(tree *montecarlo.Tree, keys ...string) *montecarlo.Node {
+	node := tree.Root()
+	for _, k := range keys {
+		child := node.GetChild(k)
+		if child == nil {
+			n, err := montecarlo.NewNode(node.NumPlayers())
+			if err != nil {
+				panic(err)
+			}
+			n.State = actions[k](node.State.Copy())
+			child = node.SetChild(k, &n)
+		}
+		node = child
+	}
+	return node
+}
+
+// keyedNodes walks every node below the passed node, failing if two distinct
+// nodes share a state key, and returns the number of distinct nodes
+func keyedNodes(t *testing.T, root *montecarlo.Node) int {
+	seen := make(map[*montecarlo.Node]bool)
+	byKey := make(map[montecarlo.Key]*montecarlo.Node)
+	stack := []*montecarlo.Node{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		key := n.State.(gameState).StateKey()
+		if other, ok := byKey[key]; ok {
+			t.Fatalf("two nodes for the same state:\n%v", other.State)
+		}
+		byKey[key] = n
+		for _, k := range []string{"X", "O"} {
+			for i := 0; i < 3; i++ {
+				for j := 0; j < 3; j++ {
+					if child := n.GetChild(cellKey(k, i, j)); child != nil {
+						stack = append(stack, child)
+					}
+				}
+			}
+		}
+	}
+	return len(seen)
+}
+
+func cellKey(player string, i, j int) string {
+	return fmt.Sprintf("SET_%v_%v_%v", player, i, j)
+}
+
+func TestTranspositionsShareNodes(t *testing.T) {
+	makeActions()
+	tree, err := montecarlo.NewTree(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.EnableTranspositions()
+	first := addPath(&tree, "SET_X_0_0", "SET_O_1_1", "SET_X_2_2")
+	second := addPath(&tree, "SET_X_2_2", "SET_O_1_1", "SET_X_0_0")
+	assert.True(t, first == second)
+	// the root, four nodes for the first path and one for the second, which
+	// meets the first after two moves
+	assert.Equal(t, 6, tree.NumNodes())
+
+	// without transpositions every path has its own nodes
+	plain, err := montecarlo.NewTree(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first = addPath(&plain, "SET_X_0_0", "SET_O_1_1", "SET_X_2_2")
+	second = addPath(&plain, "SET_X_2_2", "SET_O_1_1", "SET_X_0_0")
+	assert.False(t, first == second)
+	assert.Equal(t, 7, plain.NumNodes())
+}
+
+func TestTranspositionsSearch(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai.Tree().EnableTranspositions()
+	ai.SetRand(rand.New(rand.NewSource(1)))
+	result, err := ai.Search(3000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := ai.Tree().Root()
+	assert.Equal(t, int64(3000), root.Visits())
+	assert.Equal(t, int64(3000), result.Iterations)
+	assert.Equal(t, keyedNodes(t, root), ai.Tree().NumNodes())
+	// no two paths meet after a single move
+	total := int64(0)
+	for _, child := range result.Children {
+		total += child.Visits
+	}
+	assert.Equal(t, int64(3000), total)
+}
+
+func TestTranspositionsMergeByStateKey(t *testing.T) {
+	makeActions()
+	tree, err := montecarlo.NewTree(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.EnableTranspositions()
+	ours := addPath(&tree, "SET_X_0_0", "SET_O_1_1", "SET_X_2_2")
+	ours.AddVisits(3)
+
+	// the other tree reaches the same state in another order
+	other, err := montecarlo.NewTree(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs := addPath(&other, "SET_X_2_2", "SET_O_1_1", "SET_X_0_0")
+	theirs.AddVisits(2)
+
+	if err := tree.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	merged := addPath(&tree, "SET_X_2_2", "SET_O_1_1", "SET_X_0_0")
+	assert.True(t, merged == ours)
+	assert.Equal(t, int64(5), ours.Visits())
+	assert.Equal(t, keyedNodes(t, tree.Root()), tree.NumNodes())
+}
+
+func TestTranspositionsRootParallel(t *testing.T) {
+	makeActions()
+	ai, err := montecarlo.NewMultiplayerMCTS(2, initState(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai.Tree().EnableTranspositions()
+	ai.SetRand(rand.New(rand.NewSource(1)))
+	if _, err := ai.RootParallelSearch(4, 500, 1); err != nil {
+		t.Fatal(err)
+	}
+	root := ai.Tree().Root()
+	assert.Equal(t, int64(4*500), root.Visits())
+	assert.Equal(t, keyedNodes(t, root), ai.Tree().NumNodes())
+}
@@ -1,50 +1,109 @@
 package montecarlo
 
+import "math/rand"
+
 // Tree contains all the information needed to progress a MCTS: a root
 // montecarlo.Node and a set of possible actions.
 type Tree struct {
 	possibleActions map[Key]Action
-	root            Node
+	root            *Node
 }
 
 // NewTree is a constructor for a montecarlo.Tree struct.
 func NewTree(numPlayers uint, initialState State, possibleActions map[Key]Action) (Tree, error) {
 	node, err := NewNode(numPlayers)
 	node.State = initialState
+	node.learning = &learning{stats: &playoutStats{}}
+	// the root follows its state's policy, as every other node does
+	if initialState != nil && initialState.Policy() != nil {
+		node.policy = initialState.Policy()
+	}
 	return Tree{
-		root:            node,
+		root:            &node,
 		possibleActions: possibleActions,
 	}, err
 }
 
 // Root returns the root montecarlo.Node of the tree.
-func (tree *Tree) Root() Node {
+func (tree *Tree) Root() *Node {
 	return tree.root
 }
 
+// SetRand sets the random source used by every node in the tree that has not
+// been given its own. Searches of a tree with a seeded source are reproducible,
+// except for tree-parallel searches.
+func (tree *Tree) SetRand(r *rand.Rand) {
+	tree.Root().rand = r
+}
+
 // PossibleActions returns the set of possible actions defined in the
 // montecarlo.Tree struct.
 func (tree *Tree) PossibleActions() map[Key]Action {
 	return tree.possibleActions
 }
 
-// Copy creates a deep copy of this tree.
+// Copy creates a deep copy of this tree, none of the nodes of the copy are
+// shared with the original.
 func (tree *Tree) Copy() *Tree {
 	actions := make(map[Key]Action)
 	for k, v := range tree.possibleActions {
 		actions[k] = v
 	}
-	root := tree.Root()
-	rootCpy := root.Copy()
-	// will not throw any error since we're already using a valid player count
-	cpy, _ := NewTree(root.NumPlayers(), root.State, actions)
-	cpy.root = *rootCpy
-	return &cpy
+	return &Tree{
+		root:            tree.Root().Copy(),
+		possibleActions: actions,
+	}
 }
 
 // Merge two trees together: add all nodes from other into this tree. If both
 // trees have the same node, then their Score and Visit values are added.
 func (tree *Tree) Merge(other Tree) error {
+	return tree.Root().Merge(*other.Root())
+}
+
+// NumNodes returns the number of nodes in the tree, including the root.
+func (tree *Tree) NumNodes() int {
+	return tree.Root().Size()
+}
+
+// Advance makes the child reached by taking the action with the passed key from
+// the root the new root of the tree, keeping its subtree and discarding the
+// rest of the tree. If the root has no such child yet, but the action is legal
+// from the root's state, a new root is created by taking the action.
+func (tree *Tree) Advance(key Key) error {
 	root := tree.Root()
-	return root.Merge(other.Root())
+	child := root.GetChild(key)
+	if child == nil {
+		action, ok := root.State.LegalActions()[key]
+		if !ok {
+			return IllegalChildKey{key}
+		}
+		n, err := NewNode(root.NumPlayers())
+		if err != nil {
+			return err
+		}
+		n.State = action(root.State.Copy())
+		n.policy = n.State.Policy()
+		child = &n
+	}
+	// detach the new root from its parent, keeping the tree's random source and
+	// playout statistics
+	root.RemoveChild(key)
+	child.parent = nil
+	if child.rand == nil {
+		child.rand = root.rand
+	}
+	if child.learning == nil {
+		child.learning = &learning{stats: root.playoutStats()}
+	}
+	if root.table() != nil {
+		// only states reachable from the new root stay in the table, shared
+		// nodes created below the rest of the tree need a new parent
+		child.shared.table = nil
+		table := newTranspositions()
+		child.size = table.register(child)
+		table.adopt(child)
+	}
+	tree.root = child
+	return nil
 }
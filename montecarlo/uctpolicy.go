@@ -3,24 +3,40 @@ package montecarlo
 import (
 	"fmt"
 	"math/rand"
-	"time"
 )
 
 // UCTPolicy is based on the UCT algorithm outlined by (Browne et al. 2012: A
 // Survey of Monte Carlo Tree Search Methods - IEEE transactions on
 // computational intelligence and AI in games, vol. 4, no. 1).
-type UCTPolicy struct{}
+//
+// Playout chooses the actions taken during simulation, actions are chosen
+// uniformly at random if it is nil.
+type UCTPolicy struct {
+	Playout PlayoutPolicy
+}
 
 /******** IMPLEMENT Policy ********/
 
-// Select selects the child with the highest UCB
+// Select selects the child with the highest UCB, or samples an outcome at
+// chance nodes.
 func (p UCTPolicy) Select(node *Node, explorationParam float64) *Node {
 	//_, n := node.selectBestLeaf(expl)
 	n := node
 	for n != nil && (n.IsRoot() || !n.IsTerminal()) {
+		if child, expanded := n.selectOutcome(); child != nil {
+			if expanded {
+				return child
+			}
+			n = child
+			continue
+		}
 		if !n.IsExhausted() {
 			return p.Expand(n, explorationParam)
 		}
+		if n.IsLeaf() {
+			// a terminal root
+			break
+		}
 		_, n = n.selectBestChild(explorationParam)
 	}
 	return n
@@ -38,7 +54,7 @@ func (p UCTPolicy) Expand(node *Node, explorationParam float64) *Node {
 		}
 	}
 	// choose an action from the set of untried actions
-	index, action := randomAction(untried)
+	index, action := randomAction(node.Rand(), untried)
 	if action == nil {
 		return node
 	}
@@ -49,58 +65,103 @@ func (p UCTPolicy) Expand(node *Node, explorationParam float64) *Node {
 	}
 	n.State = (*action)(node.State.Copy())
 	n.policy = n.State.Policy()
-	node.SetChild(index, &n)
-	return &n
+	return node.SetChild(index, &n)
 }
 
-// Simulate by stochastically selecting legal moves until the end of the
-// simulation is reached.
+// Simulate by selecting legal moves with the playout policy until the end of
+// the simulation is reached.
 func (p UCTPolicy) Simulate(node *Node) float64 {
 	score := float64(0)
 	n := 1
 	//take the average of n simulations?
 	for i := 0; i < n; i++ {
-		state := node.State.Copy()
-		// take random actions ad nauseum
-		for {
-			legalActions := state.LegalActions()
-			if len(legalActions) <= 0 {
-				break
-			}
-			_, action := randomAction(legalActions)
-			state = (*action)(state)
-		}
+		state, _ := playout(node, p.Playout)
 		score += state.Score(state.Player())
 	}
 	return score / float64(n)
 }
 
+// playout takes legal actions chosen by the passed playout policy (uniformly
+// at random if it is nil), starting from the state of the passed node, until
+// no more actions can be taken or a PlayoutCutoff ends it early. Returns the
+// final state along with the actions that were taken.
+func playout(node *Node, policy PlayoutPolicy) (State, []PlayedAction) {
+	if policy == nil {
+		policy = UniformPlayout{}
+	}
+	r := node.Rand()
+	state := node.State.Copy()
+	played := make([]PlayedAction, 0)
+	// adaptive policies choose from, and learn from, every action of the
+	// iteration
+	adaptive, ok := policy.(adaptivePlayout)
+	stats := node.playoutStats()
+	var history []PlayedAction
+	if ok && stats != nil {
+		history = node.pathActions()
+	}
+	// take actions ad nauseum
+	for {
+		legalActions := state.LegalActions()
+		if len(legalActions) <= 0 {
+			break
+		}
+		if scores, ok := playoutCutoff(policy, state, len(played)); ok {
+			state = evaluatedState{state, scores}
+			break
+		}
+		var key Key
+		if history != nil {
+			key = adaptive.chooseWith(stats, history, state, legalActions, r)
+		} else {
+			key = policy.Choose(state, legalActions, r)
+		}
+		action, ok := legalActions[key]
+		if !ok {
+			panic(fmt.Sprintf("%v", IllegalChildKey{key}))
+		}
+		played = append(played, PlayedAction{state.Player(), key})
+		if history != nil {
+			history = append(history, played[len(played)-1])
+		}
+		state = action(state)
+	}
+	if history != nil {
+		adaptive.learn(stats, history, state, node.NumPlayers())
+	}
+	return state, played
+}
+
 // Backpropagate propagates the same score up the tree until the root is
 // reached; the number of visits is also incremented at each node on the way.
 func (p UCTPolicy) Backpropagate(node *Node, score float64) {
-	player := node.Player()
-	n := node
-	for n != nil {
-		n.SetScore(player, n.Score(player)+score)
-		n.AddVisit()
-		n = n.Parent()
-	}
+	p.BackpropagateN(node, score, 1)
+}
+
+// BackpropagateN propagates the total score of a number of simulations up the
+// tree until the root is reached; the number of visits is increased by the
+// number of simulations at each node on the way.
+func (p UCTPolicy) BackpropagateN(node *Node, score float64, visits int64) {
+	p.backpropagatePath(parentPath(node), Result{Score: score}, visits)
 }
 
-// randomAction returns a random string, action pair from a map of actions
-func randomAction(actions map[Key]Action) (Key, *Action) {
-	numActions := len(actions)
-	target := 0
-	if numActions > 0 {
-		rand.Seed(time.Now().UTC().UnixNano())
-		target = rand.Intn(numActions)
+func (p UCTPolicy) backpropagatePath(path path, result Result, visits int64) {
+	player := path.node().Player()
+	for _, e := range path {
+		e.node.SetScore(player, e.node.Score(player)+result.Score)
+		e.node.addVisitsFrom(e.parent, visits)
 	}
-	i := 0
-	for k, v := range actions {
-		if i == target {
-			return k, &v
-		}
-		i++
+}
+
+// randomAction returns a random key, action pair from a map of actions, using
+// the passed random source. The same source will always pick the same action
+// from the same map.
+func randomAction(r *rand.Rand, actions map[Key]Action) (Key, *Action) {
+	if len(actions) <= 0 {
+		return "", nil
 	}
-	return "", nil
+	keys := sortedActionKeys(actions)
+	k := keys[r.Intn(len(keys))]
+	action := actions[k]
+	return k, &action
 }
@@ -0,0 +1,76 @@
+package montecarlo
+
+// JobKind describes what a worker is being asked to do with a Job.
+type JobKind int
+
+const (
+	// SimulationJob asks a worker to simulate a playout from a single node.
+	SimulationJob JobKind = iota
+	// SubtreeJob asks a worker to search a tree for a number of iterations.
+	SubtreeJob
+)
+
+// Job is a unit of work handed to a Worker by a Scheduler.
+type Job struct {
+	Kind JobKind
+	// Node is the node to simulate from in a SimulationJob. It is always
+	// detached from any tree, so simulating from it can't affect a search.
+	Node *Node
+	// Tree is the tree to search in a SubtreeJob. The worker has sole ownership
+	// of the tree until it returns its result.
+	Tree *Tree
+	// Iterations is the number of iterations to search for in a SubtreeJob.
+	Iterations int64
+	// ExplorationParam is the exploration parameter used for selection in a
+	// SubtreeJob.
+	ExplorationParam float64
+}
+
+// Result is the outcome of a Job carried out by a Worker.
+type Result struct {
+	// Score is the score of the playout of a SimulationJob.
+	Score float64
+	// Played is the actions taken during the playout of a SimulationJob, if
+	// the node's policy is an AMAFPolicy.
+	Played []PlayedAction
+	// Scores is the final score of every player after the playout of a
+	// SimulationJob, if the node's policy is a ScoresPolicy.
+	Scores []float64
+	// Tree is the searched tree of a SubtreeJob.
+	Tree *Tree
+	// Err is non-nil if the worker failed to carry out the job.
+	Err error
+}
+
+// Worker is anything that can carry out jobs for a Scheduler. Work may be
+// called concurrently for different workers, but never concurrently for the
+// same worker.
+type Worker interface {
+	Work(job Job) Result
+}
+
+// LocalWorker carries out jobs on the goroutine it is called from.
+type LocalWorker struct{}
+
+// LocalWorkers creates n local workers.
+func LocalWorkers(n int) []Worker {
+	workers := make([]Worker, n)
+	for i := range workers {
+		workers[i] = LocalWorker{}
+	}
+	return workers
+}
+
+// Work carries out the passed job.
+func (w LocalWorker) Work(job Job) Result {
+	switch job.Kind {
+	case SimulationJob:
+		return simulate(job.Node)
+	case SubtreeJob:
+		for i := int64(0); i < job.Iterations; i++ {
+			iterate(job.Tree.Root(), job.ExplorationParam)
+		}
+		return Result{Tree: job.Tree}
+	}
+	return Result{Err: UnknownJobKind(job.Kind)}
+}